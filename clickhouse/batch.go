@@ -0,0 +1,133 @@
+package clickhouse
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/ra-company/database"
+
+	"github.com/ClickHouse/clickhouse-go/v2"
+)
+
+// InsertBatch inserts rows into a table using the native batch API of the driver.
+// The rows are sent in the binary column format, so the server does not parse VALUES tuples.
+// Rows are given as a slice of structs or pointers to structs. The columns are taken from the `ch` tags
+// of the struct fields; untagged fields and fields tagged with `ch:"-"` are skipped,
+// and fields of embedded structs are flattened.
+// The function logs the execution time, the number of rows and the number of bytes written by the server.
+//
+// Parameters:
+//   - ctx (context.Context): The context for the operation.
+//   - model (string): The name of the model being inserted, used for logging.
+//   - table (string): The name of the table to insert into.
+//   - rows (any): A slice of structs or pointers to structs with `ch` tags.
+//
+// Returns:
+//   - error: An error if the rows cannot be mapped or the execution fails, or nil if it succeeds.
+func (dst *ClickHouseClient) InsertBatch(ctx context.Context, model string, table string, rows any) error {
+	value := reflect.ValueOf(rows)
+	if value.Kind() != reflect.Slice {
+		return fmt.Errorf("%w: unsupported rows type %T", database.ErrorIncorrectParameters, rows)
+	}
+
+	elem := value.Type().Elem()
+	pointer := elem.Kind() == reflect.Pointer
+	if pointer {
+		elem = elem.Elem()
+	}
+	if elem.Kind() != reflect.Struct {
+		return fmt.Errorf("%w: unsupported rows type %T", database.ErrorIncorrectParameters, rows)
+	}
+
+	columns := chColumns(elem)
+	if len(columns) == 0 {
+		return fmt.Errorf("%w: %s has no fields with ch tags", database.ErrorIncorrectParameters, elem)
+	}
+	if value.Len() == 0 {
+		return nil
+	}
+
+	return dst.insertRows(ctx, model, table, columns, value.Len(), func(i int) any {
+		row := value.Index(i)
+		if pointer {
+			return row.Interface()
+		}
+		return row.Addr().Interface()
+	})
+}
+
+// insertRows inserts n rows returned by row into the columns of a table with a native batch,
+// and logs the execution time, the number of rows and the number of bytes written by the server.
+func (dst *ClickHouseClient) insertRows(ctx context.Context, model, table string, columns []string, n int, row func(i int) any) error {
+	start := time.Now()
+	dst.inFlight.Add(1)
+	defer dst.inFlight.Add(-1)
+
+	query := fmt.Sprintf("INSERT INTO %s (%s)", table, strings.Join(columns, ", "))
+	written, err := dst.sendBatch(ctx, query, n, row)
+	dst.logQuery(ctx, "\033[1m\033[36mCH %s Create (%.2f ms, %d rows, %d bytes)\033[1m \033[32m%s\033[0m", model, float64(time.Since(start))/1000000, n, written, query)
+
+	return err
+}
+
+// sendBatch prepares a batch for the insert query, appends n rows returned by row and sends the batch.
+// The batch is aborted if a row cannot be appended.
+// The number of bytes written is taken from the progress packets sent by the server, so the data is not encoded twice;
+// it is 0 if the server does not report it, such as over HTTP.
+//
+// Returns:
+//   - uint64: The number of uncompressed bytes written by the server.
+//   - error: An error if the execution fails, or nil if it succeeds.
+func (dst *ClickHouseClient) sendBatch(ctx context.Context, query string, n int, row func(i int) any) (uint64, error) {
+	var written uint64
+	ctx = clickhouse.Context(ctx, clickhouse.WithProgress(func(p *clickhouse.Progress) {
+		written += p.WroteBytes
+	}))
+
+	batch, err := dst.client.PrepareBatch(ctx, query)
+	if err != nil {
+		return 0, err
+	}
+
+	for i := range n {
+		v := row(i)
+		if reflect.ValueOf(v).IsNil() {
+			batch.Abort()
+			return 0, fmt.Errorf("%w: row %d is nil", database.ErrorIncorrectParameters, i)
+		}
+		if err := batch.AppendStruct(v); err != nil {
+			batch.Abort()
+			return 0, fmt.Errorf("row %d: %w", i, err)
+		}
+	}
+
+	err = batch.Send()
+
+	return written, err
+}
+
+// chColumns returns the column names of a struct type mapped with `ch` tags, in the order of declaration.
+// Fields of embedded structs without a tag are flattened into the result.
+func chColumns(t reflect.Type) []string {
+	res := []string{}
+	for i := range t.NumField() {
+		field := t.Field(i)
+		tag, ok := field.Tag.Lookup("ch")
+
+		if !ok && field.Anonymous && field.Type.Kind() == reflect.Struct {
+			res = append(res, chColumns(field.Type)...)
+			continue
+		}
+
+		if !ok || tag == "" || tag == "-" || !field.IsExported() {
+			continue
+		}
+
+		res = append(res, tag)
+	}
+
+	return res
+}
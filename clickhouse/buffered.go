@@ -0,0 +1,322 @@
+package clickhouse
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/ra-company/database"
+)
+
+var (
+	ErrorBufferFull = fmt.Errorf("buffer full") // Returned by BufferedWriter.Write with the BufferDrop policy when the buffer of a table is full
+)
+
+const (
+	DefaultBufferMaxRows = 10000       // Default number of rows of a table that triggers a flush
+	DefaultBufferMaxAge  = time.Second // Default age of the oldest row of a table that triggers a flush
+)
+
+// BufferPolicy defines what BufferedWriter.Write does when the buffer of a table is full.
+type BufferPolicy int
+
+const (
+	BufferBlock BufferPolicy = iota // Write waits until the buffer is flushed or the context is done
+	BufferDrop                      // Write drops the row and returns ErrorBufferFull
+)
+
+// BufferConfig holds the settings of a BufferedWriter. Zero values are replaced with the defaults.
+type BufferConfig struct {
+	MaxRows  int                                     // Number of rows of a table that triggers a flush, DefaultBufferMaxRows if zero
+	MaxAge   time.Duration                           // Age of the oldest row of a table that triggers a flush, DefaultBufferMaxAge if zero
+	Capacity int                                     // Maximum number of rows of a table kept in memory, including the rows being flushed, 10 × MaxRows if zero
+	Policy   BufferPolicy                            // What Write does when the buffer of a table is full
+	Retry    database.Retry                          // Defines how a failed flush is retried
+	OnError  func(table string, rows int, err error) // Called when a flush fails after all retries and its rows are dropped, can be nil
+}
+
+// BufferedWriter accumulates rows in memory per table and inserts them with the native batch API,
+// turning many tiny inserts into a few large ones, as MergeTree tables expect.
+// A table is flushed in the background when it has MaxRows rows or its oldest row is older than MaxAge.
+// It is created by ClickHouseClient.BufferedWriter and is flushed and closed by ClickHouseClient.Stop.
+type BufferedWriter struct {
+	client  *ClickHouseClient
+	cfg     BufferConfig
+	mu      sync.Mutex
+	tables  map[string]*tableBuffer
+	space   chan struct{} // Closed and replaced when a flush completes, to wake up blocked writers
+	kick    chan struct{} // Wakes up the background flusher when a table is full
+	done    chan struct{} // Closed to stop the background flusher
+	stopped chan struct{} // Closed when the background flusher has exited
+	flushMu sync.Mutex    // Serializes flushes of the background flusher and Flush
+	closed  bool
+	ctx     context.Context    // Context of the background flushes, canceled when Close gives up waiting for them
+	cancel  context.CancelFunc // Cancels ctx
+}
+
+// tableBuffer holds the rows buffered for a table.
+type tableBuffer struct {
+	model    string
+	rowType  reflect.Type
+	columns  []string
+	rows     []any
+	first    time.Time // Time the oldest buffered row was written
+	flushing int       // Number of rows of the table being flushed
+}
+
+// pendingBatch holds the rows of a table taken out of its buffer to be flushed.
+type pendingBatch struct {
+	table  string
+	buffer *tableBuffer
+	rows   []any
+}
+
+// BufferedWriter creates a buffered writer inserting rows through the client and starts its background flusher.
+// The writer is flushed and closed when the client is stopped.
+//
+// Parameters:
+//   - cfg (BufferConfig): The settings of the writer.
+//
+// Returns:
+//   - *BufferedWriter: The new writer.
+func (dst *ClickHouseClient) BufferedWriter(cfg BufferConfig) *BufferedWriter {
+	if cfg.MaxRows <= 0 {
+		cfg.MaxRows = DefaultBufferMaxRows
+	}
+	if cfg.MaxAge <= 0 {
+		cfg.MaxAge = DefaultBufferMaxAge
+	}
+	if cfg.Capacity <= 0 {
+		cfg.Capacity = 10 * cfg.MaxRows
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	writer := &BufferedWriter{
+		client:  dst,
+		cfg:     cfg,
+		tables:  map[string]*tableBuffer{},
+		space:   make(chan struct{}),
+		kick:    make(chan struct{}, 1),
+		done:    make(chan struct{}),
+		stopped: make(chan struct{}),
+		ctx:     ctx,
+		cancel:  cancel,
+	}
+	go writer.run()
+
+	dst.writersMu.Lock()
+	dst.writers = append(dst.writers, writer)
+	dst.writersMu.Unlock()
+
+	return writer
+}
+
+// Write adds a row to the buffer of a table. The row is a struct, or a pointer to a struct, with `ch` tags,
+// and all rows of a table must have the same type. A struct row is copied, a pointer row must not be changed
+// until it is flushed. When the buffer of the table is full, Write waits or drops the row according to the policy.
+//
+// Parameters:
+//   - ctx (context.Context): The context for the operation, used to stop waiting for room in the buffer.
+//   - model (string): The name of the model being inserted, used for logging.
+//   - table (string): The name of the table to insert into.
+//   - row (any): The row to insert.
+//
+// Returns:
+//   - error: ErrorBufferFull if the row is dropped, an error matching database.ErrorIncorrectParameters
+//     if the row cannot be inserted into the table, the context error, or nil if the row is buffered.
+func (dst *BufferedWriter) Write(ctx context.Context, model string, table string, row any) error {
+	value := reflect.ValueOf(row)
+	if value.Kind() == reflect.Pointer && !value.IsNil() {
+		value = value.Elem()
+	} else if value.Kind() == reflect.Struct {
+		copied := reflect.New(value.Type())
+		copied.Elem().Set(value)
+		row = copied.Interface()
+	}
+	if value.Kind() != reflect.Struct {
+		return fmt.Errorf("%w: unsupported row type %T", database.ErrorIncorrectParameters, row)
+	}
+
+	for {
+		dst.mu.Lock()
+		if dst.closed {
+			dst.mu.Unlock()
+			return fmt.Errorf("%w: buffered writer is closed", database.ErrorIncorrectRequest)
+		}
+
+		buffer, err := dst.buffer(model, table, value.Type())
+		if err != nil {
+			dst.mu.Unlock()
+			return err
+		}
+
+		if len(buffer.rows)+buffer.flushing < dst.cfg.Capacity {
+			if len(buffer.rows) == 0 {
+				buffer.first = time.Now()
+			}
+			buffer.rows = append(buffer.rows, row)
+			full := len(buffer.rows) >= dst.cfg.MaxRows
+			dst.mu.Unlock()
+
+			if full {
+				select {
+				case dst.kick <- struct{}{}:
+				default:
+				}
+			}
+			return nil
+		}
+
+		space := dst.space
+		dst.mu.Unlock()
+
+		if dst.cfg.Policy == BufferDrop {
+			return ErrorBufferFull
+		}
+
+		select {
+		case <-space:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// Flush inserts the rows buffered for all tables and waits for the inserts to complete.
+//
+// Parameters:
+//   - ctx (context.Context): The context for the operation.
+//
+// Returns:
+//   - error: The error of the first failed table, or nil if it succeeds.
+func (dst *BufferedWriter) Flush(ctx context.Context) error {
+	return dst.flush(ctx, true)
+}
+
+// Close stops the background flusher and inserts the rows left in the buffers.
+// Write returns an error after Close is called. If ctx is done while a background flush is still running,
+// for example retrying a failed insert, the flush is canceled and Close returns without inserting the rows left.
+//
+// Parameters:
+//   - ctx (context.Context): The context for the operation.
+//
+// Returns:
+//   - error: The context error if ctx is done before the background flusher stops,
+//     the error of the first failed table, or nil if it succeeds.
+func (dst *BufferedWriter) Close(ctx context.Context) error {
+	dst.mu.Lock()
+	if dst.closed {
+		dst.mu.Unlock()
+		return nil
+	}
+	dst.closed = true
+	dst.mu.Unlock()
+
+	defer dst.cancel()
+
+	close(dst.done)
+	select {
+	case <-dst.stopped:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	return dst.flush(ctx, true)
+}
+
+// buffer returns the buffer of a table, creating it on the first write. It must be called with mu held.
+func (dst *BufferedWriter) buffer(model, table string, rowType reflect.Type) (*tableBuffer, error) {
+	buffer, ok := dst.tables[table]
+	if !ok {
+		columns := chColumns(rowType)
+		if len(columns) == 0 {
+			return nil, fmt.Errorf("%w: %s has no fields with ch tags", database.ErrorIncorrectParameters, rowType)
+		}
+		buffer = &tableBuffer{model: model, rowType: rowType, columns: columns}
+		dst.tables[table] = buffer
+	}
+	if buffer.rowType != rowType {
+		return nil, fmt.Errorf("%w: table %s is written with %s, not %s", database.ErrorIncorrectParameters, table, buffer.rowType, rowType)
+	}
+
+	return buffer, nil
+}
+
+// run flushes the tables that are full or too old until the writer is closed.
+func (dst *BufferedWriter) run() {
+	defer close(dst.stopped)
+
+	ticker := time.NewTicker(max(dst.cfg.MaxAge/4, 10*time.Millisecond))
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-dst.done:
+			return
+		case <-ticker.C:
+		case <-dst.kick:
+		}
+
+		dst.flush(dst.ctx, false)
+	}
+}
+
+// flush inserts the rows of the tables due for a flush, or of all tables if all is true.
+// Failed inserts are retried according to the Retry settings; if they still fail,
+// the rows are dropped, the error is logged and passed to OnError.
+func (dst *BufferedWriter) flush(ctx context.Context, all bool) error {
+	dst.flushMu.Lock()
+	defer dst.flushMu.Unlock()
+
+	var res error
+	for _, batch := range dst.due(all) {
+		table, buffer, rows := batch.table, batch.buffer, batch.rows
+		err := dst.cfg.Retry.Do(ctx, func() error {
+			return dst.client.insertRows(ctx, buffer.model, table, buffer.columns, len(rows), func(i int) any {
+				return rows[i]
+			})
+		}, func(err error, attempt int, delay time.Duration) {
+			dst.client.Warn(ctx, "ClickHouse buffered insert into %s attempt %d of %d failed, retry in %.2f ms: %v", table, attempt, dst.cfg.Retry.Attempts, float64(delay)/1000000, err)
+		})
+
+		dst.mu.Lock()
+		buffer.flushing -= len(rows)
+		close(dst.space)
+		dst.space = make(chan struct{})
+		dst.mu.Unlock()
+
+		if err != nil {
+			dst.client.Error(ctx, fmt.Errorf("ClickHouse buffered insert into %s failed, %d rows dropped: %w", table, len(rows), err))
+			if dst.cfg.OnError != nil {
+				dst.cfg.OnError(table, len(rows), err)
+			}
+			if res == nil {
+				res = err
+			}
+		}
+	}
+
+	return res
+}
+
+// due takes the rows out of the buffers of the tables due for a flush and marks them as being flushed.
+func (dst *BufferedWriter) due(all bool) []pendingBatch {
+	dst.mu.Lock()
+	defer dst.mu.Unlock()
+
+	res := []pendingBatch{}
+	for table, buffer := range dst.tables {
+		if len(buffer.rows) == 0 {
+			continue
+		}
+		if all || len(buffer.rows) >= dst.cfg.MaxRows || time.Since(buffer.first) >= dst.cfg.MaxAge {
+			res = append(res, pendingBatch{table: table, buffer: buffer, rows: buffer.rows})
+			buffer.flushing += len(buffer.rows)
+			buffer.rows = nil
+		}
+	}
+
+	return res
+}
@@ -3,9 +3,9 @@ package clickhouse
 import (
 	"context"
 	"fmt"
-	"net"
+	"maps"
 	"os"
-	"strings"
+	"sync"
 	"sync/atomic"
 	"time"
 
@@ -23,9 +23,15 @@ var (
 type ClickHouseClient struct {
 	logging.CustomLogger
 	client          driver.Conn
-	DoNotLogQueries bool         // If true, queries will not be logged
-	lastQuery       string       // Last executed query
-	inFlight        atomic.Int64 // Number of in-flight queries
+	database        string                // Name of the database the client is connected to
+	DoNotLogQueries bool                  // If true, queries will not be logged
+	lastQuery       string                // Last executed query
+	lastQueryMu     sync.Mutex            // Protects lastQuery, which is also written by the background flushes of the buffered writers
+	inFlight        atomic.Int64          // Number of in-flight queries
+	ConnectRetry    database.ConnectRetry // Defines how a failed connection to the server is retried by Connect and Start
+	AsyncSettings   clickhouse.Settings   // Settings of the asynchronous inserts made by InsertAsync, such as async_insert_busy_timeout_ms
+	writers         []*BufferedWriter     // Buffered writers flushed and closed by Stop
+	writersMu       sync.Mutex            // Protects writers
 }
 
 // Start initializes the ClickHouse client with the provided configuration.
@@ -33,6 +39,7 @@ type ClickHouseClient struct {
 // If the connection fails, it logs an error and exits the application.
 // The function also sets various connection settings such as maximum execution time, insert quorum, and compression method.
 // It logs the connection details and the ClickHouse server version upon successful connection.
+// Use StartWithConfig to change the connection settings, or Connect to handle connection errors without exiting the application.
 //
 // Parameters:
 //   - ctx (context.Context): The context for the connection.
@@ -41,65 +48,121 @@ type ClickHouseClient struct {
 //   - password (string): The password for authentication.
 //   - db (string): The name of the database to connect to.
 func (dst *ClickHouseClient) Start(ctx context.Context, hosts, username, password, db string) {
-	var err error
-	dialCount := 0
-	dst.client, err = clickhouse.Open(&clickhouse.Options{
-		Addr: strings.Split(hosts, ","),
-		Auth: clickhouse.Auth{
-			Database: db,
-			Username: username,
-			Password: password,
-		},
-		DialContext: func(ctx context.Context, addr string) (net.Conn, error) {
-			dialCount++
-			var d net.Dialer
-			return d.DialContext(ctx, "tcp", addr)
-		},
-		Debug: false,
-		Debugf: func(format string, v ...any) {
-			fmt.Printf(format, v)
-		},
-		Settings: clickhouse.Settings{
-			"max_execution_time":    60,
-			"insert_quorum":         2,
-			"insert_quorum_timeout": 60000,
-		},
-		Compression: &clickhouse.Compression{
-			Method: clickhouse.CompressionLZ4,
-		},
-		DialTimeout:          time.Second * 30,
-		MaxOpenConns:         50,
-		MaxIdleConns:         25,
-		ConnMaxLifetime:      time.Duration(10) * time.Minute,
-		ConnOpenStrategy:     clickhouse.ConnOpenInOrder,
-		BlockBufferSize:      10,
-		MaxCompressionBuffer: 10240,
+	dst.StartWithConfig(ctx, Config{
+		Hosts:    hosts,
+		Username: username,
+		Password: password,
+		Database: db,
 	})
+}
 
-	if err != nil {
+// StartWithConfig initializes the ClickHouse client with the provided configuration.
+// It works the same way as Start, but also applies the protocol, TLS, query settings, compression
+// and connection pool options from the configuration.
+// If the connection fails, it logs an error and exits the application.
+//
+// Parameters:
+//   - ctx (context.Context): The context for the connection.
+//   - cfg (Config): The connection configuration.
+func (dst *ClickHouseClient) StartWithConfig(ctx context.Context, cfg Config) {
+	if err := dst.ConnectWithConfig(ctx, cfg); err != nil {
 		dst.Fatal(ctx, "ClickHouse connection error: %v", err)
 		os.Exit(1)
 	}
+}
 
-	v, err := dst.client.ServerVersion()
+// Connect initializes the ClickHouse client with the provided configuration.
+// It works the same way as Start, but returns an error instead of exiting the application if the connection fails,
+// so the caller decides whether a failed connection is fatal.
+// The connection is retried according to the ConnectRetry settings of the client.
+//
+// Parameters:
+//   - ctx (context.Context): The context for the connection.
+//   - hosts (string): The host addresses of the ClickHouse server.
+//   - username (string): The username for authentication.
+//   - password (string): The password for authentication.
+//   - db (string): The name of the database to connect to.
+//
+// Returns:
+//   - error: An error if the connection fails, or nil if it succeeds.
+func (dst *ClickHouseClient) Connect(ctx context.Context, hosts, username, password, db string) error {
+	return dst.ConnectWithConfig(ctx, Config{
+		Hosts:    hosts,
+		Username: username,
+		Password: password,
+		Database: db,
+	})
+}
 
+// ConnectWithConfig initializes the ClickHouse client with the provided configuration.
+// It works the same way as StartWithConfig, but returns an error instead of exiting the application if the connection fails.
+// The connection is retried according to the ConnectRetry settings of the client.
+//
+// Parameters:
+//   - ctx (context.Context): The context for the connection.
+//   - cfg (Config): The connection configuration.
+//
+// Returns:
+//   - error: An error if the connection fails, or nil if it succeeds.
+func (dst *ClickHouseClient) ConnectWithConfig(ctx context.Context, cfg Config) error {
+	var v *driver.ServerVersion
+	err := dst.ConnectRetry.Do(ctx, func() error {
+		var err error
+		v, err = dst.connect(cfg)
+		return err
+	}, func(err error, attempt int, delay time.Duration) {
+		dst.Warn(ctx, "ClickHouse connection attempt %d of %d failed, retry in %.2f ms: %v", attempt, dst.ConnectRetry.Attempts, float64(delay)/1000000, err)
+	})
 	if err != nil {
-		dst.Fatal(ctx, "ClickHouse connection error: %v", err)
-		os.Exit(1)
+		return err
 	}
 
-	dst.Info(ctx, "Connected to ClickHouse Database: hosts - %v, database - %v, user - %v", hosts, db, username)
+	dst.Info(ctx, "Connected to ClickHouse Database: hosts - %v, database - %v, user - %v, protocol - %v", cfg.Hosts, cfg.Database, cfg.Username, cfg.Protocol)
 	dst.Info(ctx, "ClickHouse Server Version: %v", v)
+
+	return nil
+}
+
+// connect opens the ClickHouse client and requests the server version to ensure the connection is established.
+// The client is closed if the server version cannot be requested.
+func (dst *ClickHouseClient) connect(cfg Config) (*driver.ServerVersion, error) {
+	client, err := clickhouse.Open(cfg.options(dst.Debug))
+	if err != nil {
+		return nil, err
+	}
+
+	v, err := client.ServerVersion()
+	if err != nil {
+		client.Close()
+		return nil, err
+	}
+
+	dst.client = client
+	dst.database = cfg.Database
+
+	return v, nil
 }
 
 // Stop closes the ClickHouse client connection and logs a message indicating disconnection.
 // It does not return any error, as the disconnection is expected to be successful.
 // This function is typically called when the application is shutting down or when the ClickHouse client is no longer needed.
 // It ensures that the client connection is properly closed to free up resources.
+// The buffered writers of the client are flushed and closed before the connection is closed.
 //
 // Parameters:
 //   - ctx (context.Context): The context for the operation.
 func (dst *ClickHouseClient) Stop(ctx context.Context) {
+	dst.writersMu.Lock()
+	writers := dst.writers
+	dst.writers = nil
+	dst.writersMu.Unlock()
+
+	for _, writer := range writers {
+		if err := writer.Close(ctx); err != nil {
+			dst.Error(ctx, err)
+		}
+	}
+
 	if dst.client != nil {
 		dst.client.Close()
 	}
@@ -110,6 +173,7 @@ func (dst *ClickHouseClient) Stop(ctx context.Context) {
 // It takes a context, a model name, and a query string as parameters.
 // The function logs the execution time and the query, and returns any error encountered during execution.
 // The model name is used for logging purposes to identify the operation being performed.
+// Settings, a query ID and parameters can be set for the query with WithQueryOptions.
 //
 // Parameters:
 //   - ctx (context.Context): The context for the operation.
@@ -123,15 +187,56 @@ func (dst *ClickHouseClient) Insert(ctx context.Context, model string, query str
 	dst.inFlight.Add(1)
 	defer dst.inFlight.Add(-1)
 
+	ctx, info := queryContext(ctx, nil)
 	err := dst.client.Exec(ctx, query)
-	dst.logQuery(ctx, "\033[1m\033[36mCH %s Create (%.2f ms)\033[1m \033[32m%s\033[0m", model, float64(time.Since(start))/1000000, database.OneLine(query))
+	dst.logQuery(ctx, "\033[1m\033[36mCH %s Create (%.2f ms%s)\033[1m \033[32m%s\033[0m", model, float64(time.Since(start))/1000000, info, database.OneLine(query))
+	return err
+}
+
+// InsertAsync executes an insert query on the ClickHouse database using server-side asynchronous inserts,
+// enabled with the clickhouse.WithAsync option of the driver.
+// The server buffers the data of many small inserts and writes it to the table in a single part,
+// which avoids creating a part per insert. The AsyncSettings of the client are applied to the insert,
+// overridden by the settings given for the call, so the buffering can be tuned per client and per call.
+// Settings, a query ID and parameters can also be set for the insert with WithQueryOptions.
+// The function logs the execution time and the query, and returns any error encountered during execution.
+//
+// Parameters:
+//   - ctx (context.Context): The context for the operation.
+//   - model (string): The name of the model being inserted.
+//   - query (string): The SQL query to be executed.
+//   - wait (bool): If true, waits until the data is written to the table, otherwise returns as soon as the server buffers it.
+//   - settings (...clickhouse.Settings): Settings of the asynchronous insert for this call, such as async_insert_max_data_size.
+//
+// Returns:
+//   - error: An error if the execution fails, or nil if it succeeds.
+func (dst *ClickHouseClient) InsertAsync(ctx context.Context, model string, query string, wait bool, settings ...clickhouse.Settings) error {
+	start := time.Now()
+	dst.inFlight.Add(1)
+	defer dst.inFlight.Add(-1)
+
+	ctx, info := queryContext(ctx, dst.asyncSettings(settings))
+	err := dst.client.Exec(clickhouse.Context(ctx, clickhouse.WithAsync(wait)), query)
+	dst.logQuery(ctx, "\033[1m\033[36mCH %s Create Async (%.2f ms%s)\033[1m \033[32m%s\033[0m", model, float64(time.Since(start))/1000000, info, database.OneLine(query))
 	return err
 }
 
+// asyncSettings merges the AsyncSettings of the client with the settings given for a call, the latter taking precedence.
+func (dst *ClickHouseClient) asyncSettings(settings []clickhouse.Settings) clickhouse.Settings {
+	res := clickhouse.Settings{}
+	maps.Copy(res, dst.AsyncSettings)
+	for _, s := range settings {
+		maps.Copy(res, s)
+	}
+
+	return res
+}
+
 // Update executes an update query on the ClickHouse database.
 // It takes a context, a model name, and a query string as parameters.
 // The function logs the execution time and the query, and returns the number of affected rows and any error encountered during execution.
 // The model name is used for logging purposes to identify the operation being performed.
+// Settings, a query ID and parameters can be set for the query with WithQueryOptions.
 //
 // Parameters:
 //   - ctx (context.Context): The context for the operation.
@@ -146,8 +251,9 @@ func (dst *ClickHouseClient) Update(ctx context.Context, model string, query str
 	dst.inFlight.Add(1)
 	defer dst.inFlight.Add(-1)
 
+	ctx, info := queryContext(ctx, nil)
 	err := dst.client.Exec(ctx, query)
-	dst.logQuery(ctx, "\033[1m\033[36mCH %s Update (%.2f ms)\033[1m \033[33m%s\033[0m", model, float64(time.Since(start))/1000000, database.OneLine(query))
+	dst.logQuery(ctx, "\033[1m\033[36mCH %s Update (%.2f ms%s)\033[1m \033[33m%s\033[0m", model, float64(time.Since(start))/1000000, info, database.OneLine(query))
 	return 0, err
 }
 
@@ -155,6 +261,7 @@ func (dst *ClickHouseClient) Update(ctx context.Context, model string, query str
 // It takes a context, a model name, and a query string as parameters.
 // The function logs the execution time and the query, and returns the count of rows and any error encountered during execution.
 // The model name is used for logging purposes to identify the operation being performed.
+// Settings, a query ID and parameters can be set for the query with WithQueryOptions.
 //
 // Parameters:
 //   - ctx (context.Context): The context for the operation.
@@ -170,9 +277,10 @@ func (dst *ClickHouseClient) Count(ctx context.Context, model string, query stri
 	defer dst.inFlight.Add(-1)
 
 	var n uint64
+	ctx, info := queryContext(ctx, nil)
 	err := dst.client.QueryRow(ctx, query).Scan(&n)
 
-	if dst.logQuery(ctx, "\033[1m\033[36mCH %s Count (%.2f ms)\033[1m \033[34m%s\033[0m", model, float64(time.Since(start))/1000000, database.OneLine(query)); err != nil {
+	if dst.logQuery(ctx, "\033[1m\033[36mCH %s Count (%.2f ms%s)\033[1m \033[34m%s\033[0m", model, float64(time.Since(start))/1000000, info, database.OneLine(query)); err != nil {
 		return 0, err
 	}
 
@@ -183,6 +291,7 @@ func (dst *ClickHouseClient) Count(ctx context.Context, model string, query stri
 // It takes a context, a model name, a query string, and a variadic list of destination variables.
 // The function logs the execution time and the query, and returns any error encountered during execution.
 // The model name is used for logging purposes to identify the operation being performed.
+// Settings, a query ID and parameters can be set for the query with WithQueryOptions.
 //
 // Parameters:
 //   - ctx (context.Context): The context for the operation.
@@ -197,8 +306,9 @@ func (dst *ClickHouseClient) Scan(ctx context.Context, model string, query strin
 	dst.inFlight.Add(1)
 	defer dst.inFlight.Add(-1)
 
+	ctx, info := queryContext(ctx, nil)
 	err := dst.client.QueryRow(ctx, query).Scan(dest...)
-	if dst.logQuery(ctx, "\033[1m\033[36mCH %s Load (%.2f ms)\033[1m \033[34m%s\033[0m", model, float64(time.Since(start))/1000000, database.OneLine(query)); err != nil {
+	if dst.logQuery(ctx, "\033[1m\033[36mCH %s Load (%.2f ms%s)\033[1m \033[34m%s\033[0m", model, float64(time.Since(start))/1000000, info, database.OneLine(query)); err != nil {
 		return err
 	}
 
@@ -209,6 +319,7 @@ func (dst *ClickHouseClient) Scan(ctx context.Context, model string, query strin
 // It takes a context, a model name, a query string, and a pointer to a data structure to hold the results.
 // The function logs the execution time and the query, and returns any error encountered during execution.
 // The model name is used for logging purposes to identify the operation being performed.
+// Settings, a query ID and parameters can be set for the query with WithQueryOptions.
 //
 // Parameters:
 //   - ctx (context.Context): The context for the operation.
@@ -223,8 +334,9 @@ func (dst *ClickHouseClient) Select(ctx context.Context, model string, query str
 	dst.inFlight.Add(1)
 	defer dst.inFlight.Add(-1)
 
+	ctx, info := queryContext(ctx, nil)
 	err := dst.client.Select(ctx, data, query)
-	dst.logQuery(ctx, "\033[1m\033[36mCH %s Load (%.2f ms)\033[1m \033[34m%s\033[0m", model, float64(time.Since(start))/1000000, database.OneLine(query))
+	dst.logQuery(ctx, "\033[1m\033[36mCH %s Load (%.2f ms%s)\033[1m \033[34m%s\033[0m", model, float64(time.Since(start))/1000000, info, database.OneLine(query))
 
 	return err
 }
@@ -244,15 +356,21 @@ func (dst *ClickHouseClient) LogSelect(ctx context.Context, model string, query
 }
 
 func (dst *ClickHouseClient) logQuery(args ...any) {
+	var query string
 	if len(args) > 1 {
-		dst.lastQuery = fmt.Sprintf(args[1].(string), args[2:]...)
+		query = fmt.Sprintf(args[1].(string), args[2:]...)
 	} else {
-		dst.lastQuery = fmt.Sprint(args[1:]...)
+		query = fmt.Sprint(args[1:]...)
 	}
+
+	dst.lastQueryMu.Lock()
+	dst.lastQuery = query
+	dst.lastQueryMu.Unlock()
+
 	if dst.DoNotLogQueries {
 		return
 	}
-	dst.Debug(args[0].(context.Context), dst.lastQuery)
+	dst.Debug(args[0].(context.Context), query)
 }
 
 // LastQuery returns the last executed ClickHouse query as a string.
@@ -262,6 +380,9 @@ func (dst *ClickHouseClient) logQuery(args ...any) {
 //   - A string containing the last executed query.
 func (dst *ClickHouseClient) LastQuery() string {
 	// Returns the last executed query as a string.
+	dst.lastQueryMu.Lock()
+	defer dst.lastQueryMu.Unlock()
+
 	return dst.lastQuery
 }
 
@@ -1,10 +1,18 @@
 package clickhouse
 
 import (
+	"context"
+	"reflect"
+	"sync"
 	"testing"
+	"testing/fstest"
+	"time"
 
+	"github.com/ra-company/database"
 	"github.com/ra-company/env"
 	"github.com/stretchr/testify/require"
+
+	"github.com/ClickHouse/clickhouse-go/v2"
 )
 
 func Test(t *testing.T) {
@@ -18,4 +26,286 @@ func Test(t *testing.T) {
 	require.NotEmpty(t, user, "CH_USER environment variable must be set for ClickHouse tests")
 
 	CH.Start(ctx, host, user, password, db)
+
+	migrator, err := CH.Migrator(fstest.MapFS{
+		"0001_create_test_events.up.sql":   {Data: []byte("CREATE TABLE test_events {{.OnCluster}} (id UInt64) ENGINE = MergeTree ORDER BY id")},
+		"0001_create_test_events.down.sql": {Data: []byte("DROP TABLE test_events {{.OnCluster}}")},
+	}, ".")
+	require.NoError(t, err, "Migrator() failed")
+	migrator.Table = "test_schema_migrations"
+	defer func() {
+		err := (*CH.Client()).Exec(ctx, "DROP TABLE IF EXISTS test_schema_migrations")
+		require.NoError(t, err, "failed to drop test_schema_migrations")
+	}()
+	require.NoError(t, migrator.Up(ctx), "Migrator.Up() failed")
+	statuses, err := migrator.Status(ctx)
+	require.NoError(t, err, "Migrator.Status() failed")
+	require.Len(t, statuses, 1, "Migrator.Status() should return the migration")
+	require.True(t, statuses[0].Applied, "Migrator.Status() should report the applied migration")
+
+	type testEvent struct {
+		ID uint64 `ch:"id"`
+	}
+	events := []testEvent{{ID: 1}, {ID: 2}, {ID: 3}}
+	require.NoError(t, CH.InsertBatch(ctx, "Event", "test_events", events), "InsertBatch() failed")
+	require.Regexp(t, `3 rows, [1-9]\d* bytes`, CH.LastQuery(), "InsertBatch() should log the rows and bytes written")
+	count, err := CH.Count(ctx, "Event", "SELECT count() FROM test_events")
+	require.NoError(t, err, "Count() failed")
+	require.Equal(t, uint64(len(events)), count, "InsertBatch() should insert all rows")
+
+	writer := CH.BufferedWriter(BufferConfig{MaxAge: time.Minute})
+	require.NoError(t, writer.Write(ctx, "Event", "test_events", testEvent{ID: 4}), "BufferedWriter.Write() failed")
+	require.NoError(t, writer.Flush(ctx), "BufferedWriter.Flush() failed")
+	require.NoError(t, writer.Close(ctx), "BufferedWriter.Close() failed")
+	count, err = CH.Count(ctx, "Event", "SELECT count() FROM test_events")
+	require.NoError(t, err, "Count() failed")
+	require.Equal(t, uint64(len(events)+1), count, "BufferedWriter should insert the buffered rows")
+
+	require.NoError(t, CH.InsertAsync(ctx, "Event", "INSERT INTO test_events (id) VALUES (5)", true, clickhouse.Settings{"async_insert_busy_timeout_ms": 100}), "InsertAsync() failed")
+	count, err = CH.Count(ctx, "Event", "SELECT count() FROM test_events")
+	require.NoError(t, err, "Count() failed")
+	require.Equal(t, uint64(len(events)+2), count, "InsertAsync() with wait should insert the row")
+
+	queryCtx := WithQueryOptions(ctx,
+		WithQueryID("test-query-id"),
+		WithSettings(clickhouse.Settings{"max_execution_time": 10}),
+		WithParameters(clickhouse.Parameters{"id": "2"}))
+	count, err = CH.Count(queryCtx, "Event", "SELECT count() FROM test_events WHERE id > {id:UInt64}")
+	require.NoError(t, err, "Count() with query options failed")
+	require.Equal(t, uint64(len(events)), count, "Count() should use the parameters")
+	require.Contains(t, CH.LastQuery(), "query_id test-query-id", "LastQuery() should contain the query ID")
+
+	require.NoError(t, migrator.Down(ctx, 1), "Migrator.Down() failed")
+}
+
+func TestConnectError(t *testing.T) {
+	client := ClickHouseClient{ConnectRetry: database.ConnectRetry{Attempts: 2, Delay: time.Millisecond}}
+	err := client.Connect(t.Context(), "127.0.0.1:1", "default", "", "default")
+	require.Error(t, err, "Connect() should return an error for an unreachable server")
+}
+
+func TestSplitStatements(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  []string
+	}{
+		{
+			name:  "single statement",
+			query: "CREATE TABLE t (id UInt64) ENGINE = Memory",
+			want:  []string{"CREATE TABLE t (id UInt64) ENGINE = Memory"},
+		},
+		{
+			name:  "several statements",
+			query: "CREATE TABLE a (id UInt64) ENGINE = Memory;\n\nCREATE TABLE b (id UInt64) ENGINE = Memory;\n",
+			want:  []string{"CREATE TABLE a (id UInt64) ENGINE = Memory", "CREATE TABLE b (id UInt64) ENGINE = Memory"},
+		},
+		{
+			name:  "semicolons in strings",
+			query: `INSERT INTO t VALUES ('a;b', 'it\'s;'); SELECT "x;y"`,
+			want:  []string{`INSERT INTO t VALUES ('a;b', 'it\'s;')`, `SELECT "x;y"`},
+		},
+		{
+			name:  "comments",
+			query: "-- create table; really\nCREATE TABLE t (id UInt64) ENGINE = Memory; -- trailing comment",
+			want:  []string{"CREATE TABLE t (id UInt64) ENGINE = Memory"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, splitStatements(tt.query), "splitStatements()")
+		})
+	}
+}
+
+func TestMigratorRender(t *testing.T) {
+	client := ClickHouseClient{database: "analytics"}
+	migrator, err := client.Migrator(fstest.MapFS{
+		"0001_create_events.up.sql": {Data: []byte("CREATE TABLE {{.Database}}.events {{.OnCluster}} (id UInt64) ENGINE = MergeTree ORDER BY id")},
+	}, ".")
+	require.NoError(t, err, "Migrator()")
+
+	query, err := migrator.render(migrator.migrations[0].Up)
+	require.NoError(t, err, "render()")
+	require.Equal(t, "CREATE TABLE analytics.events  (id UInt64) ENGINE = MergeTree ORDER BY id", query, "render() without cluster")
+
+	migrator.Cluster = "main"
+	query, err = migrator.render(migrator.migrations[0].Up)
+	require.NoError(t, err, "render()")
+	require.Equal(t, "CREATE TABLE analytics.events ON CLUSTER main (id UInt64) ENGINE = MergeTree ORDER BY id", query, "render() with cluster")
+
+	_, err = migrator.render("CREATE TABLE {{.Unknown}}")
+	require.ErrorIs(t, err, database.ErrorIncorrectParameters, "render() with unknown field")
+
+	engine, err := migrator.engine()
+	require.NoError(t, err, "engine() with cluster")
+	require.Equal(t, "ReplicatedReplacingMergeTree(applied_at)", engine, "engine() should replicate the default engine on a cluster")
+
+	migrator.Engine = "ReplicatedReplacingMergeTree('/clickhouse/tables/{shard}/migrations', '{replica}', applied_at)"
+	engine, err = migrator.engine()
+	require.NoError(t, err, "engine() with a replicated engine")
+	require.Equal(t, migrator.Engine, engine, "engine() should keep a replicated engine")
+
+	migrator.Engine = "MergeTree"
+	_, err = migrator.engine()
+	require.ErrorIs(t, err, database.ErrorIncorrectParameters, "engine() with a non-replicated engine on a cluster")
+
+	migrator.Cluster = ""
+	engine, err = migrator.engine()
+	require.NoError(t, err, "engine() without cluster")
+	require.Equal(t, "MergeTree", engine, "engine() without cluster")
+}
+
+func TestInsertBatchRows(t *testing.T) {
+	type Base struct {
+		CreatedAt time.Time `ch:"created_at"`
+	}
+	type event struct {
+		Base
+		ID       uint64 `ch:"id"`
+		Name     string `ch:"name"`
+		Skipped  string `ch:"-"`
+		Untagged string
+	}
+	require.Equal(t, []string{"created_at", "id", "name"}, chColumns(reflect.TypeFor[event]()), "chColumns()")
+
+	client := ClickHouseClient{}
+	require.ErrorIs(t, client.InsertBatch(t.Context(), "Event", "events", event{}), database.ErrorIncorrectParameters, "InsertBatch() with a struct")
+	require.ErrorIs(t, client.InsertBatch(t.Context(), "Event", "events", []int{1}), database.ErrorIncorrectParameters, "InsertBatch() with a slice of int")
+	require.ErrorIs(t, client.InsertBatch(t.Context(), "Event", "events", []struct{ ID uint64 }{{}}), database.ErrorIncorrectParameters, "InsertBatch() without ch tags")
+	require.NoError(t, client.InsertBatch(t.Context(), "Event", "events", []*event{}), "InsertBatch() with no rows")
+}
+
+func TestBufferedWriter(t *testing.T) {
+	type event struct {
+		ID   uint64 `ch:"id"`
+		Name string `ch:"name"`
+	}
+	type other struct {
+		ID uint64 `ch:"id"`
+	}
+
+	client := ClickHouseClient{}
+	writer := client.BufferedWriter(BufferConfig{})
+	require.Equal(t, DefaultBufferMaxRows, writer.cfg.MaxRows, "default MaxRows")
+	require.Equal(t, DefaultBufferMaxAge, writer.cfg.MaxAge, "default MaxAge")
+	require.Equal(t, 10*DefaultBufferMaxRows, writer.cfg.Capacity, "default Capacity")
+	require.NoError(t, writer.Close(t.Context()), "Close() with no rows")
+	require.ErrorIs(t, writer.Write(t.Context(), "Event", "events", event{}), database.ErrorIncorrectRequest, "Write() after Close()")
+
+	writer = client.BufferedWriter(BufferConfig{MaxRows: 10, MaxAge: time.Hour, Capacity: 2, Policy: BufferDrop})
+	require.Len(t, client.writers, 2, "registered writers")
+
+	row := event{ID: 1, Name: "first"}
+	require.NoError(t, writer.Write(t.Context(), "Event", "events", row), "Write() a struct")
+	row.Name = "changed"
+	require.NoError(t, writer.Write(t.Context(), "Event", "events", &event{ID: 2}), "Write() a pointer")
+	require.ErrorIs(t, writer.Write(t.Context(), "Event", "events", &event{ID: 3}), ErrorBufferFull, "Write() to a full buffer")
+	require.Equal(t, "first", writer.tables["events"].rows[0].(*event).Name, "struct rows are copied")
+
+	require.ErrorIs(t, writer.Write(t.Context(), "Other", "events", other{}), database.ErrorIncorrectParameters, "Write() with another type")
+	require.ErrorIs(t, writer.Write(t.Context(), "Other", "others", 1), database.ErrorIncorrectParameters, "Write() an int")
+	require.ErrorIs(t, writer.Write(t.Context(), "Other", "others", (*other)(nil)), database.ErrorIncorrectParameters, "Write() a nil pointer")
+	require.ErrorIs(t, writer.Write(t.Context(), "Other", "others", struct{ ID uint64 }{}), database.ErrorIncorrectParameters, "Write() without ch tags")
+
+	busy := client.BufferedWriter(BufferConfig{})
+	busy.flushMu.Lock()
+	busy.kick <- struct{}{}
+	require.Eventually(t, func() bool { return len(busy.kick) == 0 }, time.Second, time.Millisecond, "the background flush should start")
+	ctx, cancel := context.WithTimeout(t.Context(), 10*time.Millisecond)
+	defer cancel()
+	require.ErrorIs(t, busy.Close(ctx), context.DeadlineExceeded, "Close() should not wait for a running flush past its deadline")
+	require.ErrorIs(t, busy.ctx.Err(), context.Canceled, "Close() should cancel the running flush")
+	busy.flushMu.Unlock()
+	<-busy.stopped
+}
+
+func TestAsyncSettings(t *testing.T) {
+	client := ClickHouseClient{}
+	require.Empty(t, client.asyncSettings(nil), "asyncSettings() without settings")
+
+	client.AsyncSettings = clickhouse.Settings{"async_insert_busy_timeout_ms": 200, "async_insert_max_data_size": 1000000}
+	settings := client.asyncSettings([]clickhouse.Settings{{"async_insert_busy_timeout_ms": 50}, {"async_insert_deduplicate": 1}})
+	require.Equal(t, clickhouse.Settings{"async_insert_busy_timeout_ms": 50, "async_insert_max_data_size": 1000000, "async_insert_deduplicate": 1}, settings, "asyncSettings() should override the client settings")
+	require.Equal(t, 200, client.AsyncSettings["async_insert_busy_timeout_ms"], "asyncSettings() should not change the client settings")
+}
+
+func TestConfig(t *testing.T) {
+	var logged []any
+	debug := func(args ...any) { logged = args }
+
+	cfg := Config{Hosts: "ch1:9000,ch2:9000", Username: "user", Password: "pass", Database: "db"}
+	options := cfg.options(debug)
+	require.Equal(t, []string{"ch1:9000", "ch2:9000"}, options.Addr, "options() Addr")
+	require.Equal(t, clickhouse.Auth{Database: "db", Username: "user", Password: "pass"}, options.Auth, "options() Auth")
+	require.Equal(t, clickhouse.Native, options.Protocol, "options() default Protocol")
+	require.Equal(t, DefaultSettings, options.Settings, "options() default Settings")
+	require.Equal(t, clickhouse.CompressionLZ4, options.Compression.Method, "options() default Compression")
+	require.Equal(t, DefaultDialTimeout, options.DialTimeout, "options() default DialTimeout")
+	require.Equal(t, DefaultMaxOpenConns, options.MaxOpenConns, "options() default MaxOpenConns")
+	require.Equal(t, uint8(DefaultBlockBufferSize), options.BlockBufferSize, "options() default BlockBufferSize")
+	require.Equal(t, clickhouse.ConnOpenInOrder, options.ConnOpenStrategy, "options() default ConnOpenStrategy")
+	require.Nil(t, options.TLS, "options() default TLS")
+
+	options.Debugf("query %s took %d ms", "SELECT 1", 5)
+	require.Equal(t, "query SELECT 1 took 5 ms", logged[1], "options() Debugf should expand the arguments")
+
+	cfg.Protocol = clickhouse.HTTP
+	cfg.Settings = clickhouse.Settings{"insert_quorum": 0, "max_memory_usage": 1000000}
+	cfg.Compression = &clickhouse.Compression{Method: clickhouse.CompressionNone}
+	cfg.MaxOpenConns = 5
+	cfg.DialTimeout = time.Second
+	cfg.ConnOpenStrategy = clickhouse.ConnOpenRoundRobin
+	options = cfg.options(debug)
+	require.Equal(t, clickhouse.HTTP, options.Protocol, "options() Protocol")
+	require.Equal(t, clickhouse.Settings{"max_execution_time": 60, "insert_quorum": 0, "insert_quorum_timeout": 60000, "max_memory_usage": 1000000}, options.Settings, "options() Settings")
+	require.Equal(t, clickhouse.CompressionNone, options.Compression.Method, "options() Compression")
+	require.Equal(t, 5, options.MaxOpenConns, "options() MaxOpenConns")
+	require.Equal(t, time.Second, options.DialTimeout, "options() DialTimeout")
+	require.Equal(t, clickhouse.ConnOpenRoundRobin, options.ConnOpenStrategy, "options() ConnOpenStrategy")
+	require.Equal(t, 2, DefaultSettings["insert_quorum"], "options() should not change DefaultSettings")
+}
+
+func TestQueryOptions(t *testing.T) {
+	ctx, info := queryContext(t.Context(), nil)
+	require.Equal(t, t.Context(), ctx, "queryContext() without options should return the context")
+	require.Empty(t, info, "queryContext() without options")
+
+	ctx = WithQueryOptions(t.Context(),
+		WithQueryID("first"),
+		WithSettings(clickhouse.Settings{"max_execution_time": 10}),
+		WithParameters(clickhouse.Parameters{"id": "1"}))
+	child := WithQueryOptions(ctx,
+		WithQueryID("second"),
+		WithSettings(clickhouse.Settings{"max_memory_usage": 1000}),
+		WithParameters(clickhouse.Parameters{"name": "test"}))
+
+	options := child.Value(queryOptionsKey{}).(queryOptions)
+	require.Equal(t, "second", options.queryID, "WithQueryOptions() should replace the query ID")
+	require.Equal(t, clickhouse.Settings{"max_execution_time": 10, "max_memory_usage": 1000}, options.settings, "WithQueryOptions() should merge the settings")
+	require.Equal(t, clickhouse.Parameters{"id": "1", "name": "test"}, options.parameters, "WithQueryOptions() should merge the parameters")
+
+	parent := ctx.Value(queryOptionsKey{}).(queryOptions)
+	require.Equal(t, clickhouse.Settings{"max_execution_time": 10}, parent.settings, "WithQueryOptions() should not change the parent options")
+
+	_, info = queryContext(child, clickhouse.Settings{"async_insert": 1})
+	require.Equal(t, ", query_id second", info, "queryContext() info")
+	require.NotContains(t, options.settings, "async_insert", "queryContext() should not change the options")
+}
+
+func TestLastQueryConcurrent(t *testing.T) {
+	client := ClickHouseClient{DoNotLogQueries: true}
+
+	var wg sync.WaitGroup
+	for i := range 4 {
+		wg.Go(func() {
+			for range 100 {
+				client.logQuery(t.Context(), "CH Event Create (%d)", i)
+				_ = client.LastQuery()
+			}
+		})
+	}
+	wg.Wait()
+
+	require.Contains(t, client.LastQuery(), "CH Event Create", "LastQuery()")
 }
@@ -0,0 +1,89 @@
+package clickhouse
+
+import (
+	"cmp"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"maps"
+	"strings"
+	"time"
+
+	"github.com/ClickHouse/clickhouse-go/v2"
+)
+
+const (
+	DefaultDialTimeout          = 30 * time.Second // Default timeout of opening a connection
+	DefaultMaxOpenConns         = 50               // Default maximum number of open connections
+	DefaultMaxIdleConns         = 25               // Default maximum number of idle connections
+	DefaultConnMaxLifetime      = 10 * time.Minute // Default maximum lifetime of a connection
+	DefaultBlockBufferSize      = 10               // Default number of blocks buffered when reading the result of a query
+	DefaultMaxCompressionBuffer = 10240            // Default size in bytes of the compression buffer of a column
+)
+
+var (
+	// Default settings applied to every query, overridden by Config.Settings
+	DefaultSettings = clickhouse.Settings{
+		"max_execution_time":    60,
+		"insert_quorum":         2,
+		"insert_quorum_timeout": 60000,
+	}
+)
+
+// Config holds the ClickHouse connection settings used by ClickHouseClient.StartWithConfig.
+// Zero values are replaced with the defaults, which are the settings used by Start.
+type Config struct {
+	Hosts                string                      // Comma-separated list of host addresses (with port) of the ClickHouse servers
+	Username             string                      // Username for authentication
+	Password             string                      // Password for authentication
+	Database             string                      // Name of the database to connect to
+	Protocol             clickhouse.Protocol         // Protocol of the connection, clickhouse.Native or clickhouse.HTTP
+	TLS                  *tls.Config                 // TLS configuration of the connection, no TLS if nil
+	Settings             clickhouse.Settings         // Settings applied to every query, merged over DefaultSettings; set insert_quorum to 0 for a single replica
+	Compression          *clickhouse.Compression     // Compression of the data sent and received, LZ4 if nil
+	DialTimeout          time.Duration               // Timeout of opening a connection, DefaultDialTimeout if zero
+	MaxOpenConns         int                         // Maximum number of open connections, DefaultMaxOpenConns if zero
+	MaxIdleConns         int                         // Maximum number of idle connections, DefaultMaxIdleConns if zero
+	ConnMaxLifetime      time.Duration               // Maximum lifetime of a connection, DefaultConnMaxLifetime if zero
+	ConnOpenStrategy     clickhouse.ConnOpenStrategy // Order in which the hosts are tried, clickhouse.ConnOpenInOrder by default
+	BlockBufferSize      uint8                       // Number of blocks buffered when reading the result of a query, DefaultBlockBufferSize if zero
+	MaxCompressionBuffer int                         // Size in bytes of the compression buffer of a column, DefaultMaxCompressionBuffer if zero
+	Debug                bool                        // If true, the debug messages of the driver are written to the debug log of the client
+}
+
+// options builds the options of the ClickHouse driver from the configuration.
+// The debug messages of the driver are written with debug, such as the Debug method of the client.
+func (dst *Config) options(debug func(args ...any)) *clickhouse.Options {
+	settings := clickhouse.Settings{}
+	maps.Copy(settings, DefaultSettings)
+	maps.Copy(settings, dst.Settings)
+
+	compression := dst.Compression
+	if compression == nil {
+		compression = &clickhouse.Compression{Method: clickhouse.CompressionLZ4}
+	}
+
+	return &clickhouse.Options{
+		Protocol: dst.Protocol,
+		TLS:      dst.TLS,
+		Addr:     strings.Split(dst.Hosts, ","),
+		Auth: clickhouse.Auth{
+			Database: dst.Database,
+			Username: dst.Username,
+			Password: dst.Password,
+		},
+		Debug: dst.Debug,
+		Debugf: func(format string, v ...any) {
+			debug(context.Background(), fmt.Sprintf(format, v...))
+		},
+		Settings:             settings,
+		Compression:          compression,
+		DialTimeout:          cmp.Or(dst.DialTimeout, DefaultDialTimeout),
+		MaxOpenConns:         cmp.Or(dst.MaxOpenConns, DefaultMaxOpenConns),
+		MaxIdleConns:         cmp.Or(dst.MaxIdleConns, DefaultMaxIdleConns),
+		ConnMaxLifetime:      cmp.Or(dst.ConnMaxLifetime, DefaultConnMaxLifetime),
+		ConnOpenStrategy:     dst.ConnOpenStrategy,
+		BlockBufferSize:      cmp.Or(dst.BlockBufferSize, DefaultBlockBufferSize),
+		MaxCompressionBuffer: cmp.Or(dst.MaxCompressionBuffer, DefaultMaxCompressionBuffer),
+	}
+}
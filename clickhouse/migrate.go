@@ -0,0 +1,315 @@
+package clickhouse
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/ra-company/database"
+)
+
+const (
+	DefaultMigrationsTable  = "schema_migrations"              // Default name of the table recording the applied migrations
+	DefaultMigrationsEngine = "ReplacingMergeTree(applied_at)" // Default engine of the table recording the applied migrations
+)
+
+// Migrator applies and reverts versioned schema migrations read from a file system.
+// It is created by ClickHouseClient.Migrator. Applied migrations are recorded in a ReplacingMergeTree bookkeeping table:
+// applying or reverting a migration inserts a new row for its version, and the latest row wins.
+//
+// Migration files are templates, so the same files can be used with a single server and with a cluster:
+//   - {{.OnCluster}} is replaced with "ON CLUSTER <cluster>", or with nothing if Cluster is empty.
+//   - {{.Cluster}} is replaced with the name of the cluster.
+//   - {{.Database}} is replaced with the name of the current database.
+//
+// A migration file may contain several statements separated by semicolons; they are executed one by one.
+// ClickHouse has no transactions, so a failed migration may be partially applied and is not recorded.
+// ClickHouse has no advisory locks either: migrations are serialized within the process only.
+type Migrator struct {
+	client     *ClickHouseClient
+	migrations []database.Migration
+	mu         sync.Mutex
+	Table      string // Name of the bookkeeping table, optionally qualified with a database name
+	Engine     string // Engine of the bookkeeping table; with a cluster, it must be replicated, and the default is replaced with ReplicatedReplacingMergeTree
+	Cluster    string // Name of the cluster used for ON CLUSTER, empty for a single server
+}
+
+// migrationTemplate holds the values available in the migration templates.
+type migrationTemplate struct {
+	OnCluster string
+	Cluster   string
+	Database  string
+}
+
+// Migrator creates a migrator for the migrations in a directory of the file system.
+// Migration files are named "<version>_<name>.up.sql" and "<version>_<name>.down.sql",
+// such as "0001_create_events.up.sql". The file system is usually an embed.FS.
+//
+// Parameters:
+//   - fsys (fs.FS): The file system containing the migration files.
+//   - dir (string): The directory of the migration files, "." for the root of the file system.
+//
+// Returns:
+//   - *Migrator: The new migrator using the default bookkeeping table and engine.
+//   - error: An error if the migrations cannot be loaded.
+func (dst *ClickHouseClient) Migrator(fsys fs.FS, dir string) (*Migrator, error) {
+	migrations, err := database.LoadMigrations(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Migrator{client: dst, migrations: migrations, Table: DefaultMigrationsTable, Engine: DefaultMigrationsEngine}, nil
+}
+
+// Up applies all pending migrations in ascending order of versions.
+// It refuses to run if an already applied migration file was changed.
+//
+// Parameters:
+//   - ctx (context.Context): The context for the operation.
+//
+// Returns:
+//   - error: An error if a migration fails or an applied migration was modified, or nil if it succeeds.
+func (dst *Migrator) Up(ctx context.Context) error {
+	return dst.Goto(ctx, database.MigrationLatest)
+}
+
+// Down reverts the last n applied migrations in descending order of versions.
+//
+// Parameters:
+//   - ctx (context.Context): The context for the operation.
+//   - n (int): The number of migrations to revert.
+//
+// Returns:
+//   - error: An error if a migration fails or has no down file, or nil if it succeeds.
+func (dst *Migrator) Down(ctx context.Context, n int) error {
+	return dst.run(ctx, func(applied []database.AppliedMigration) error {
+		_, down, err := database.PlanMigrations(dst.migrations, applied, database.DownTarget(applied, n))
+		if err != nil {
+			return err
+		}
+
+		return dst.migrate(ctx, nil, down)
+	})
+}
+
+// Goto migrates the database to the given version. Pending migrations up to the version are applied,
+// and applied migrations above the version are reverted.
+//
+// Parameters:
+//   - ctx (context.Context): The context for the operation.
+//   - version (uint64): The target version, 0 to revert all migrations.
+//
+// Returns:
+//   - error: An error if a migration fails, the version is unknown or an applied migration was modified, or nil if it succeeds.
+func (dst *Migrator) Goto(ctx context.Context, version uint64) error {
+	return dst.run(ctx, func(applied []database.AppliedMigration) error {
+		up, down, err := database.PlanMigrations(dst.migrations, applied, version)
+		if err != nil {
+			return err
+		}
+
+		return dst.migrate(ctx, up, down)
+	})
+}
+
+// Status returns the state of every migration: found in the files, applied to the database, or both.
+//
+// Parameters:
+//   - ctx (context.Context): The context for the operation.
+//
+// Returns:
+//   - []database.MigrationStatus: The status of every migration, sorted by version.
+//   - error: An error if the operation fails, or nil if it succeeds.
+func (dst *Migrator) Status(ctx context.Context) ([]database.MigrationStatus, error) {
+	var res []database.MigrationStatus
+	err := dst.run(ctx, func(applied []database.AppliedMigration) error {
+		res = database.MigrationStatuses(dst.migrations, applied)
+		return nil
+	})
+
+	return res, err
+}
+
+// run creates the bookkeeping table if needed and calls fn with the applied migrations.
+func (dst *Migrator) run(ctx context.Context, fn func(applied []database.AppliedMigration) error) error {
+	dst.mu.Lock()
+	defer dst.mu.Unlock()
+
+	engine, err := dst.engine()
+	if err != nil {
+		return err
+	}
+
+	query, err := dst.render("CREATE TABLE IF NOT EXISTS " + dst.Table + ` {{.OnCluster}} (
+		version UInt64,
+		name String,
+		checksum String,
+		applied UInt8,
+		applied_at DateTime64(3, 'UTC')
+	) ENGINE = ` + engine + ` ORDER BY version`)
+	if err != nil {
+		return err
+	}
+	if err := dst.exec(ctx, "Exec", "\033[34m", query); err != nil {
+		return err
+	}
+
+	applied := []database.AppliedMigration{}
+	err = dst.client.Select(ctx, "Migration", "SELECT version, name, checksum, applied_at FROM "+dst.Table+" FINAL WHERE applied = 1 ORDER BY version", &applied)
+	if err != nil {
+		return err
+	}
+
+	return fn(applied)
+}
+
+// migrate reverts and then applies the planned migrations.
+func (dst *Migrator) migrate(ctx context.Context, up, down []database.Migration) error {
+	for _, migration := range down {
+		if err := dst.apply(ctx, migration, false); err != nil {
+			return err
+		}
+	}
+	for _, migration := range up {
+		if err := dst.apply(ctx, migration, true); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// apply executes the statements of a migration and records it as applied or reverted.
+func (dst *Migrator) apply(ctx context.Context, migration database.Migration, up bool) error {
+	start := time.Now()
+
+	source, action, color, result := migration.Up, "Up", "\033[32m", "applied"
+	if !up {
+		source, action, color, result = migration.Down, "Down", "\033[31m", "reverted"
+	}
+
+	query, err := dst.render(source)
+	if err != nil {
+		return fmt.Errorf("migration %d_%s: %w", migration.Version, migration.Name, err)
+	}
+
+	for _, statement := range splitStatements(query) {
+		if err := dst.exec(ctx, action, color, statement); err != nil {
+			return fmt.Errorf("migration %d_%s: %w", migration.Version, migration.Name, err)
+		}
+	}
+
+	applied := uint8(0)
+	if up {
+		applied = 1
+	}
+	err = dst.exec(ctx, "Create", "\033[32m", "INSERT INTO "+dst.Table+" (version, name, checksum, applied, applied_at) VALUES (?, ?, ?, ?, ?)",
+		migration.Version, migration.Name, migration.Checksum, applied, time.Now().UTC())
+	if err != nil {
+		return fmt.Errorf("migration %d_%s: %w", migration.Version, migration.Name, err)
+	}
+
+	dst.client.Info(ctx, "ClickHouse migration %d_%s %s (%.2f ms)", migration.Version, migration.Name, result, float64(time.Since(start))/1000000)
+
+	return nil
+}
+
+// exec executes a statement of the migrator and logs it.
+func (dst *Migrator) exec(ctx context.Context, action, color, query string, args ...any) error {
+	start := time.Now()
+	dst.client.inFlight.Add(1)
+	defer dst.client.inFlight.Add(-1)
+
+	err := dst.client.client.Exec(ctx, query, args...)
+	dst.client.logQuery(ctx, "\033[1m\033[36mCH Migration %s (%.2f ms)\033[1m %s%s\033[0m", action, float64(time.Since(start))/1000000, color, database.OneLine(query))
+	if err != nil {
+		dst.client.Error(ctx, err)
+	}
+
+	return err
+}
+
+// engine returns the engine of the bookkeeping table. With a cluster, the table must be replicated,
+// otherwise each applied migration is recorded only on the node that received the insert
+// and the other nodes apply it again: the default engine is replaced with its replicated variant,
+// and another non-replicated engine is rejected.
+func (dst *Migrator) engine() (string, error) {
+	if dst.Cluster == "" || strings.HasPrefix(dst.Engine, "Replicated") || strings.HasPrefix(dst.Engine, "Shared") {
+		return dst.Engine, nil
+	}
+	if dst.Engine == DefaultMigrationsEngine {
+		return "Replicated" + dst.Engine, nil
+	}
+
+	return "", fmt.Errorf("%w: engine %s of the migrations table is not replicated on cluster %s", database.ErrorIncorrectParameters, dst.Engine, dst.Cluster)
+}
+
+// render executes the migration template with the cluster and database of the migrator.
+func (dst *Migrator) render(source string) (string, error) {
+	tmpl, err := template.New("migration").Option("missingkey=error").Parse(source)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", database.ErrorIncorrectParameters, err)
+	}
+
+	data := migrationTemplate{Cluster: dst.Cluster, Database: dst.client.database}
+	if dst.Cluster != "" {
+		data.OnCluster = "ON CLUSTER " + dst.Cluster
+	}
+
+	var res strings.Builder
+	if err := tmpl.Execute(&res, data); err != nil {
+		return "", fmt.Errorf("%w: %v", database.ErrorIncorrectParameters, err)
+	}
+
+	return res.String(), nil
+}
+
+// splitStatements splits a migration into statements separated by semicolons.
+// Semicolons inside quoted strings and quoted identifiers do not split statements.
+// Line comments are removed and empty statements are skipped.
+func splitStatements(query string) []string {
+	res := []string{}
+	var current strings.Builder
+	var quote byte
+
+	flush := func() {
+		if statement := strings.TrimSpace(current.String()); statement != "" {
+			res = append(res, statement)
+		}
+		current.Reset()
+	}
+
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+		switch {
+		case quote != 0:
+			current.WriteByte(c)
+			if c == '\\' && i+1 < len(query) {
+				i++
+				current.WriteByte(query[i])
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"' || c == '`':
+			quote = c
+			current.WriteByte(c)
+		case c == '-' && strings.HasPrefix(query[i:], "--"):
+			end := strings.IndexByte(query[i:], '\n')
+			if end < 0 {
+				end = len(query) - i
+			}
+			i += end - 1
+		case c == ';':
+			flush()
+		default:
+			current.WriteByte(c)
+		}
+	}
+	flush()
+
+	return res
+}
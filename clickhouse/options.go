@@ -0,0 +1,139 @@
+package clickhouse
+
+import (
+	"context"
+	"maps"
+
+	"github.com/ClickHouse/clickhouse-go/v2"
+)
+
+// QueryOption sets an option of the queries made with a context returned by WithQueryOptions.
+type QueryOption func(*queryOptions)
+
+// queryOptions holds the options of the queries made with a context.
+type queryOptions struct {
+	settings   clickhouse.Settings
+	queryID    string
+	parameters clickhouse.Parameters
+}
+
+// queryOptionsKey is the context key of the query options.
+type queryOptionsKey struct{}
+
+// WithSettings sets ClickHouse settings of the queries, such as max_execution_time or max_memory_usage.
+// The settings override the settings of the connection.
+//
+// Parameters:
+//   - settings (clickhouse.Settings): The settings of the queries.
+//
+// Returns:
+//   - QueryOption: The option to pass to WithQueryOptions.
+func WithSettings(settings clickhouse.Settings) QueryOption {
+	return func(o *queryOptions) {
+		if o.settings == nil {
+			o.settings = clickhouse.Settings{}
+		}
+		maps.Copy(o.settings, settings)
+	}
+}
+
+// WithQueryID sets the ID of the queries, visible in system.query_log and system.processes
+// and used to cancel a query with KILL QUERY. The ID is written to the debug log of the query.
+//
+// Parameters:
+//   - queryID (string): The ID of the queries.
+//
+// Returns:
+//   - QueryOption: The option to pass to WithQueryOptions.
+func WithQueryID(queryID string) QueryOption {
+	return func(o *queryOptions) {
+		o.queryID = queryID
+	}
+}
+
+// WithParameters sets the values of the server-side parameters of the queries,
+// written as {name:Type} in the query, such as "SELECT * FROM events WHERE id = {id:UInt64}".
+//
+// Parameters:
+//   - parameters (clickhouse.Parameters): The values of the parameters, by name.
+//
+// Returns:
+//   - QueryOption: The option to pass to WithQueryOptions.
+func WithParameters(parameters clickhouse.Parameters) QueryOption {
+	return func(o *queryOptions) {
+		if o.parameters == nil {
+			o.parameters = clickhouse.Parameters{}
+		}
+		maps.Copy(o.parameters, parameters)
+	}
+}
+
+// WithQueryOptions returns a context applying the options to the queries of Select, Scan, Count, Insert,
+// InsertAsync and Update made with it. Options already set in ctx are kept: settings and parameters are merged,
+// and the query ID is replaced.
+//
+//	ctx := clickhouse.WithQueryOptions(ctx,
+//		clickhouse.WithQueryID(id),
+//		clickhouse.WithSettings(driver.Settings{"max_execution_time": 300}),
+//		clickhouse.WithParameters(driver.Parameters{"id": "42"}))
+//	err := clickhouse.CH.Select(ctx, "Event", "SELECT * FROM events WHERE id = {id:UInt64}", &events)
+//
+// Parameters:
+//   - ctx (context.Context): The parent context.
+//   - opts (...QueryOption): The options of the queries.
+//
+// Returns:
+//   - context.Context: The context with the options.
+func WithQueryOptions(ctx context.Context, opts ...QueryOption) context.Context {
+	options := queryOptions{}
+	if parent, ok := ctx.Value(queryOptionsKey{}).(queryOptions); ok {
+		options.queryID = parent.queryID
+		options.settings = maps.Clone(parent.settings)
+		options.parameters = maps.Clone(parent.parameters)
+	}
+
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	return context.WithValue(ctx, queryOptionsKey{}, options)
+}
+
+// queryContext applies the query options set in ctx with WithQueryOptions, and the extra settings,
+// to the context passed to the driver.
+//
+// Returns:
+//   - context.Context: The context for the driver.
+//   - string: The query ID formatted for the debug log, or an empty string if it is not set.
+func queryContext(ctx context.Context, extra clickhouse.Settings) (context.Context, string) {
+	options, _ := ctx.Value(queryOptionsKey{}).(queryOptions)
+
+	settings := maps.Clone(options.settings)
+	if len(extra) > 0 {
+		if settings == nil {
+			settings = clickhouse.Settings{}
+		}
+		maps.Copy(settings, extra)
+	}
+
+	opts := []clickhouse.QueryOption{}
+	if settings != nil {
+		opts = append(opts, clickhouse.WithSettings(settings))
+	}
+	if options.parameters != nil {
+		opts = append(opts, clickhouse.WithParameters(options.parameters))
+	}
+	if options.queryID != "" {
+		opts = append(opts, clickhouse.WithQueryID(options.queryID))
+	}
+	if len(opts) == 0 {
+		return ctx, ""
+	}
+
+	info := ""
+	if options.queryID != "" {
+		info = ", query_id " + options.queryID
+	}
+
+	return clickhouse.Context(ctx, opts...), info
+}
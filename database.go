@@ -1,11 +1,13 @@
 package database
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"reflect"
 	"regexp"
 	"strings"
+	"time"
 
 	"github.com/google/uuid"
 )
@@ -16,8 +18,84 @@ var (
 	ErrorDatabaseError       = fmt.Errorf("database error")
 	ErrorNotFound            = fmt.Errorf("not found")
 	ErrorIncorrectID         = fmt.Errorf("incorrect ID")
+	ErrorUniqueViolation     = fmt.Errorf("unique violation")
+	ErrorForeignKeyViolation = fmt.Errorf("foreign key violation")
+	ErrorCheckViolation      = fmt.Errorf("check violation")
+	ErrorNotNullViolation    = fmt.Errorf("not null violation")
+	ErrorMigrationModified   = fmt.Errorf("migration modified")
 )
 
+// Retry defines how a failed operation, such as a write, is retried.
+// The delay between attempts starts from Delay and is doubled after each failed attempt, up to MaxDelay.
+// The zero value makes a single attempt without retries.
+type Retry struct {
+	Attempts int           // Attempts: is the total number of attempts. Values less than 2 disable retries.
+	Delay    time.Duration // Delay: is the delay before the first retry.
+	MaxDelay time.Duration // MaxDelay: is the upper bound of the delay between attempts. Zero means no bound.
+}
+
+// Do calls fn until it succeeds, the attempts are exhausted or the context is done.
+// After each failed attempt that is going to be retried, notify is called with the error,
+// the number of the failed attempt and the delay before the next one.
+//
+// Parameters:
+//   - ctx: The context for the operation, used for cancellation and timeout.
+//   - fn: The function performing the operation.
+//   - notify: The function called before each retry, can be nil.
+//
+// Returns:
+//   - nil if the operation succeeds, the error of the last attempt, or the context error.
+func (dst *Retry) Do(ctx context.Context, fn func() error, notify func(err error, attempt int, delay time.Duration)) error {
+	delay := dst.Delay
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil || attempt >= dst.Attempts {
+			return err
+		}
+
+		if notify != nil {
+			notify(err, attempt, delay)
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+
+		delay *= 2
+		if dst.MaxDelay > 0 && delay > dst.MaxDelay {
+			delay = dst.MaxDelay
+		}
+	}
+}
+
+// ConnectRetry defines how a failed connection to a database server is retried.
+// The delay between attempts starts from Delay and is doubled after each failed attempt, up to MaxDelay.
+// The zero value makes a single attempt without retries.
+type ConnectRetry struct {
+	Attempts int           // Attempts: is the total number of connection attempts. Values less than 2 disable retries.
+	Delay    time.Duration // Delay: is the delay before the first retry.
+	MaxDelay time.Duration // MaxDelay: is the upper bound of the delay between attempts. Zero means no bound.
+}
+
+// Do calls connect until it succeeds, the attempts are exhausted or the context is done.
+// After each failed attempt that is going to be retried, notify is called with the error,
+// the number of the failed attempt and the delay before the next one.
+//
+// Parameters:
+//   - ctx: The context for the operation, used for cancellation and timeout.
+//   - connect: The function establishing the connection.
+//   - notify: The function called before each retry, can be nil.
+//
+// Returns:
+//   - nil if the connection is established, the error of the last attempt, or the context error.
+func (dst *ConnectRetry) Do(ctx context.Context, connect func() error, notify func(err error, attempt int, delay time.Duration)) error {
+	return (*Retry)(dst).Do(ctx, connect, notify)
+}
+
 // ArrayToString converts a slice of any slice type to a Database array string representation.
 // It formats the slice into a string that can be used in SQL queries as an array.
 // If the input slice is empty, it returns "[]".
@@ -2,7 +2,10 @@ package database
 
 import (
 	"fmt"
+	"slices"
 	"testing"
+	"testing/fstest"
+	"time"
 
 	"github.com/stretchr/testify/require"
 )
@@ -52,3 +55,135 @@ func ExampleOneLine() {
 	// Hello, World!
 	// SELECT * FROM test_table WHERE id = 1
 }
+
+func TestConnectRetry(t *testing.T) {
+	ctx := t.Context()
+
+	t.Run("succeeds after retries", func(t *testing.T) {
+		retry := ConnectRetry{Attempts: 3, Delay: time.Millisecond, MaxDelay: 2 * time.Millisecond}
+		calls := 0
+		notified := []int{}
+		err := retry.Do(ctx, func() error {
+			calls++
+			if calls < 3 {
+				return ErrorDatabaseError
+			}
+			return nil
+		}, func(err error, attempt int, delay time.Duration) {
+			require.ErrorIs(t, err, ErrorDatabaseError, "Do() notify error")
+			require.LessOrEqual(t, delay, retry.MaxDelay, "Do() notify delay")
+			notified = append(notified, attempt)
+		})
+		require.NoError(t, err, "Do()")
+		require.Equal(t, 3, calls, "Do() calls")
+		require.Equal(t, []int{1, 2}, notified, "Do() notified attempts")
+	})
+
+	t.Run("returns last error", func(t *testing.T) {
+		retry := ConnectRetry{Attempts: 2}
+		calls := 0
+		err := retry.Do(ctx, func() error {
+			calls++
+			return ErrorDatabaseError
+		}, nil)
+		require.ErrorIs(t, err, ErrorDatabaseError, "Do()")
+		require.Equal(t, 2, calls, "Do() calls")
+	})
+
+	t.Run("zero value makes one attempt", func(t *testing.T) {
+		retry := ConnectRetry{}
+		calls := 0
+		err := retry.Do(ctx, func() error {
+			calls++
+			return ErrorDatabaseError
+		}, nil)
+		require.ErrorIs(t, err, ErrorDatabaseError, "Do()")
+		require.Equal(t, 1, calls, "Do() calls")
+	})
+}
+
+func TestLoadMigrations(t *testing.T) {
+	fsys := fstest.MapFS{
+		"migrations/0002_add_email.up.sql":      {Data: []byte("ALTER TABLE users ADD email TEXT;")},
+		"migrations/0001_create_users.up.sql":   {Data: []byte("CREATE TABLE users (id SERIAL PRIMARY KEY);")},
+		"migrations/0001_create_users.down.sql": {Data: []byte("DROP TABLE users;")},
+		"migrations/README.md":                  {Data: []byte("ignored")},
+	}
+
+	migrations, err := LoadMigrations(fsys, "migrations")
+	require.NoError(t, err, "LoadMigrations()")
+	require.Len(t, migrations, 2, "LoadMigrations() count")
+	require.Equal(t, uint64(1), migrations[0].Version, "LoadMigrations() first version")
+	require.Equal(t, "create_users", migrations[0].Name, "LoadMigrations() first name")
+	require.Equal(t, "DROP TABLE users;", migrations[0].Down, "LoadMigrations() first down")
+	require.Len(t, migrations[0].Checksum, 64, "LoadMigrations() checksum")
+	require.Empty(t, migrations[1].Down, "LoadMigrations() second down")
+
+	fsys["migrations/0003_orphan.down.sql"] = &fstest.MapFile{Data: []byte("SELECT 1;")}
+	_, err = LoadMigrations(fsys, "migrations")
+	require.ErrorIs(t, err, ErrorIncorrectParameters, "LoadMigrations() without up file")
+
+	delete(fsys, "migrations/0003_orphan.down.sql")
+	fsys["migrations/0002_other.up.sql"] = &fstest.MapFile{Data: []byte("SELECT 1;")}
+	_, err = LoadMigrations(fsys, "migrations")
+	require.ErrorIs(t, err, ErrorIncorrectParameters, "LoadMigrations() with duplicate version")
+}
+
+func TestPlanMigrations(t *testing.T) {
+	migrations := []Migration{
+		{Version: 1, Name: "one", Up: "1", Down: "-1", Checksum: "c1"},
+		{Version: 2, Name: "two", Up: "2", Down: "-2", Checksum: "c2"},
+		{Version: 3, Name: "three", Up: "3", Checksum: "c3"},
+	}
+	applied := []AppliedMigration{
+		{Version: 2, Name: "two", Checksum: "c2"},
+		{Version: 1, Name: "one", Checksum: "c1"},
+	}
+	versions := func(migrations []Migration) []uint64 {
+		res := []uint64{}
+		for _, migration := range migrations {
+			res = append(res, migration.Version)
+		}
+		return res
+	}
+
+	tests := []struct {
+		name    string
+		applied []AppliedMigration
+		target  uint64
+		up      []uint64
+		down    []uint64
+		err     error
+	}{
+		{name: "latest", applied: applied, target: MigrationLatest, up: []uint64{3}, down: []uint64{}},
+		{name: "all from scratch", applied: nil, target: MigrationLatest, up: []uint64{1, 2, 3}, down: []uint64{}},
+		{name: "goto lower", applied: applied, target: 1, up: []uint64{}, down: []uint64{2}},
+		{name: "revert all", applied: applied, target: 0, up: []uint64{}, down: []uint64{2, 1}},
+		{name: "out of order", applied: applied[:1], target: 2, up: []uint64{1}, down: []uint64{}},
+		{name: "unknown version", applied: applied, target: 7, err: ErrorIncorrectParameters},
+		{name: "no down file", applied: append(slices.Clone(applied), AppliedMigration{Version: 3, Checksum: "c3"}), target: 2, err: ErrorIncorrectRequest},
+		{name: "modified", applied: []AppliedMigration{{Version: 1, Checksum: "old"}}, target: MigrationLatest, err: ErrorMigrationModified},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			up, down, err := PlanMigrations(migrations, tt.applied, tt.target)
+			if tt.err != nil {
+				require.ErrorIs(t, err, tt.err, "PlanMigrations()")
+				return
+			}
+			require.NoError(t, err, "PlanMigrations()")
+			require.Equal(t, tt.up, versions(up), "PlanMigrations() up")
+			require.Equal(t, tt.down, versions(down), "PlanMigrations() down")
+		})
+	}
+
+	require.Equal(t, uint64(1), DownTarget(applied, 1), "DownTarget(1)")
+	require.Equal(t, uint64(0), DownTarget(applied, 5), "DownTarget(5)")
+	require.Equal(t, uint64(2), DownTarget(applied, 0), "DownTarget(0)")
+
+	statuses := MigrationStatuses(migrations[1:], append(slices.Clone(applied), AppliedMigration{Version: 2, Checksum: "changed"})[1:])
+	require.Len(t, statuses, 3, "MigrationStatuses() count")
+	require.True(t, statuses[0].Missing, "MigrationStatuses() missing migration")
+	require.True(t, statuses[1].Modified, "MigrationStatuses() modified migration")
+	require.False(t, statuses[2].Applied, "MigrationStatuses() pending migration")
+}
@@ -0,0 +1,242 @@
+package database
+
+import (
+	"cmp"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"math"
+	"path"
+	"regexp"
+	"slices"
+	"strconv"
+	"time"
+)
+
+const (
+	MigrationLatest uint64 = math.MaxUint64 // MigrationLatest: is the target version applying all migrations.
+)
+
+var (
+	migrationFileRe = regexp.MustCompile(`^(\d+)_(.+)\.(up|down)\.sql$`) // migrationFileRe: matches migration file names such as "0001_create_users.up.sql".
+)
+
+// Migration is a versioned schema change read from a pair of up and down SQL files.
+type Migration struct {
+	Version  uint64 // Version: is the version of the migration, taken from the numeric prefix of the file name.
+	Name     string // Name: is the name of the migration, taken from the file name after the version.
+	Up       string // Up: is the SQL applying the migration.
+	Down     string // Down: is the SQL reverting the migration, empty if there is no down file.
+	Checksum string // Checksum: is the hex-encoded SHA-256 of the up SQL, used to detect changes of applied migrations.
+}
+
+// MigrationStatus describes the state of a migration in a database.
+type MigrationStatus struct {
+	Version   uint64    // Version: is the version of the migration.
+	Name      string    // Name: is the name of the migration.
+	Applied   bool      // Applied: is true if the migration is applied to the database.
+	AppliedAt time.Time // AppliedAt: is the time the migration was applied, zero if it is not applied.
+	Modified  bool      // Modified: is true if the migration is applied, but its up file was changed afterwards.
+	Missing   bool      // Missing: is true if the migration is applied, but its files are not found.
+}
+
+// LoadMigrations reads the migrations from a directory of the file system.
+// Migration files are named "<version>_<name>.up.sql" and "<version>_<name>.down.sql",
+// such as "0001_create_users.up.sql". The down file is optional. Other files are ignored.
+// The file system is usually an embed.FS, so the migrations are built into the binary.
+//
+// Parameters:
+//   - fsys: The file system containing the migration files.
+//   - dir: The directory of the migration files, "." for the root of the file system.
+//
+// Returns:
+//   - The migrations sorted by version.
+//   - An error matching ErrorIncorrectParameters if the files are inconsistent, or an error if they cannot be read.
+func LoadMigrations(fsys fs.FS, dir string) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := map[uint64]*Migration{}
+	for _, entry := range entries {
+		match := migrationFileRe.FindStringSubmatch(entry.Name())
+		if entry.IsDir() || match == nil {
+			continue
+		}
+
+		version, err := strconv.ParseUint(match[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%w: migration %s has incorrect version: %v", ErrorIncorrectParameters, entry.Name(), err)
+		}
+
+		data, err := fs.ReadFile(fsys, path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = migration
+		}
+		if migration.Name != match[2] {
+			return nil, fmt.Errorf("%w: migration version %d is used by %s and %s", ErrorIncorrectParameters, version, migration.Name, match[2])
+		}
+
+		if match[3] == "up" {
+			migration.Up = string(data)
+			sum := sha256.Sum256(data)
+			migration.Checksum = hex.EncodeToString(sum[:])
+		} else {
+			migration.Down = string(data)
+		}
+	}
+
+	res := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.Checksum == "" {
+			return nil, fmt.Errorf("%w: migration %d_%s has no up file", ErrorIncorrectParameters, migration.Version, migration.Name)
+		}
+		res = append(res, *migration)
+	}
+	slices.SortFunc(res, func(a, b Migration) int {
+		return cmp.Compare(a.Version, b.Version)
+	})
+
+	return res, nil
+}
+
+// AppliedMigration is a migration recorded as applied in the bookkeeping table of a database.
+type AppliedMigration struct {
+	Version   uint64    `db:"version" ch:"version"`       // Version: is the version of the applied migration.
+	Name      string    `db:"name" ch:"name"`             // Name: is the name of the applied migration.
+	Checksum  string    `db:"checksum" ch:"checksum"`     // Checksum: is the checksum of the up SQL at the time the migration was applied.
+	AppliedAt time.Time `db:"applied_at" ch:"applied_at"` // AppliedAt: is the time the migration was applied.
+}
+
+// PlanMigrations computes the migrations to apply and to revert to bring a database to the target version.
+// Pending migrations with versions up to the target are applied in ascending order,
+// including the ones older than the latest applied migration. Applied migrations with versions
+// above the target are reverted in descending order.
+// Planning is refused if an applied migration was modified, because the database may not match the files anymore.
+//
+// Parameters:
+//   - migrations: The migrations loaded with LoadMigrations, sorted by version.
+//   - applied: The migrations recorded as applied in the database.
+//   - target: The target version, 0 to revert all migrations, or MigrationLatest to apply all of them.
+//
+// Returns:
+//   - The migrations to apply, in order.
+//   - The migrations to revert, in order.
+//   - An error matching ErrorMigrationModified if an applied migration was modified,
+//     ErrorIncorrectParameters if the target version is unknown,
+//     or ErrorIncorrectRequest if a migration to revert has no down SQL.
+func PlanMigrations(migrations []Migration, applied []AppliedMigration, target uint64) ([]Migration, []Migration, error) {
+	files := map[uint64]Migration{}
+	for _, migration := range migrations {
+		files[migration.Version] = migration
+	}
+
+	done := map[uint64]bool{}
+	for _, migration := range applied {
+		done[migration.Version] = true
+		if file, ok := files[migration.Version]; ok && file.Checksum != migration.Checksum {
+			return nil, nil, fmt.Errorf("%w: migration %d_%s was changed after it was applied", ErrorMigrationModified, migration.Version, migration.Name)
+		}
+	}
+
+	if _, ok := files[target]; !ok && !done[target] && target != 0 && target != MigrationLatest {
+		return nil, nil, fmt.Errorf("%w: unknown migration version %d", ErrorIncorrectParameters, target)
+	}
+
+	up := []Migration{}
+	for _, migration := range migrations {
+		if migration.Version <= target && !done[migration.Version] {
+			up = append(up, migration)
+		}
+	}
+
+	down := []Migration{}
+	for _, migration := range slices.Backward(sortedApplied(applied)) {
+		if migration.Version <= target {
+			continue
+		}
+		file, ok := files[migration.Version]
+		if !ok {
+			return nil, nil, fmt.Errorf("%w: migration %d_%s is applied, but its files are not found", ErrorIncorrectRequest, migration.Version, migration.Name)
+		}
+		if file.Down == "" {
+			return nil, nil, fmt.Errorf("%w: migration %d_%s has no down file", ErrorIncorrectRequest, migration.Version, migration.Name)
+		}
+		down = append(down, file)
+	}
+
+	return up, down, nil
+}
+
+// DownTarget returns the version a database is migrated to when the last n applied migrations are reverted.
+// Only the migrations to revert planned for this version should be used, so pending migrations are not applied.
+//
+// Parameters:
+//   - applied: The migrations recorded as applied in the database.
+//   - n: The number of migrations to revert.
+//
+// Returns:
+//   - The target version for PlanMigrations, 0 if all migrations are reverted.
+func DownTarget(applied []AppliedMigration, n int) uint64 {
+	sorted := sortedApplied(applied)
+	n = max(n, 0)
+	if n >= len(sorted) {
+		return 0
+	}
+
+	return sorted[len(sorted)-n-1].Version
+}
+
+// MigrationStatuses merges the migration files with the applied migrations of a database.
+//
+// Parameters:
+//   - migrations: The migrations loaded with LoadMigrations, sorted by version.
+//   - applied: The migrations recorded as applied in the database.
+//
+// Returns:
+//   - The status of every migration, sorted by version.
+func MigrationStatuses(migrations []Migration, applied []AppliedMigration) []MigrationStatus {
+	done := map[uint64]AppliedMigration{}
+	for _, migration := range applied {
+		done[migration.Version] = migration
+	}
+
+	res := []MigrationStatus{}
+	for _, migration := range migrations {
+		status := MigrationStatus{Version: migration.Version, Name: migration.Name}
+		if row, ok := done[migration.Version]; ok {
+			status.Applied = true
+			status.AppliedAt = row.AppliedAt
+			status.Modified = row.Checksum != migration.Checksum
+			delete(done, migration.Version)
+		}
+		res = append(res, status)
+	}
+
+	for _, row := range done {
+		res = append(res, MigrationStatus{Version: row.Version, Name: row.Name, Applied: true, AppliedAt: row.AppliedAt, Missing: true})
+	}
+	slices.SortFunc(res, func(a, b MigrationStatus) int {
+		return cmp.Compare(a.Version, b.Version)
+	})
+
+	return res
+}
+
+// sortedApplied returns a copy of the applied migrations sorted by version.
+func sortedApplied(applied []AppliedMigration) []AppliedMigration {
+	res := slices.Clone(applied)
+	slices.SortFunc(res, func(a, b AppliedMigration) int {
+		return cmp.Compare(a.Version, b.Version)
+	})
+
+	return res
+}
@@ -0,0 +1,150 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"github.com/georgysavva/scany/v2/pgxscan"
+	"github.com/ra-company/database"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// Batch collects independent statements to be sent to PostgreSQL in a single network round trip.
+// It is created by PostgresClient.Batch or PostgresTx.Batch, filled with Select and Exec calls and sent with Send.
+// Each statement keeps its own model name and result target. The results are stored in the targets
+// when Send completes, and the statements are logged in the same format as the other operations.
+type Batch struct {
+	client *PostgresClient // client: is the PostgreSQL client used for logging.
+	q      querier         // q: is the connection pool or transaction the batch is sent to.
+	batch  pgx.Batch       // batch: is the underlying pgx batch.
+	items  []*batchItem    // items: are the queued statements, in the order they were added.
+	last   time.Time       // last: is the time the previous result was processed, used to time the statements.
+}
+
+// batchItem describes a statement queued in a Batch, used for logging.
+type batchItem struct {
+	model   string        // model: is the name of the model, used for logging.
+	action  string        // action: is the name of the operation, such as Load or Exec.
+	color   string        // color: is the terminal color sequence used for the query.
+	query   string        // query: is the SQL query string.
+	args    []any         // args: are the arguments bound to the query placeholders.
+	done    bool          // done: is true if the result of the statement was processed.
+	elapsed time.Duration // elapsed: is the time between the previous result and the result of this statement.
+}
+
+// Batch creates a new empty batch of statements to be sent through the connection pool.
+//
+// Returns:
+//   - A pointer to the new Batch.
+func (dst *PostgresClient) Batch() *Batch {
+	return &Batch{client: dst, q: dst.client}
+}
+
+// Batch creates a new empty batch of statements to be sent inside the transaction.
+//
+// Returns:
+//   - A pointer to the new Batch.
+func (dst *PostgresTx) Batch() *Batch {
+	return &Batch{client: dst.client, q: dst.tx}
+}
+
+// Select queues a select query whose result will be scanned into the provided data structure.
+//
+// Parameters:
+//   - model: The name of the model being queried, used for logging.
+//   - query: The SQL query string to be executed in the database.
+//   - data: A pointer to the data structure where the result will be scanned into.
+//   - args: Optional arguments bound to the $1..$n placeholders of the query.
+//
+// Returns:
+//   - The Batch itself, to allow chaining.
+func (dst *Batch) Select(model string, query string, data any, args ...any) *Batch {
+	item := dst.add(model, "Load", "\033[34m", query, args)
+	dst.batch.Queue(query, args...).Query(func(rows pgx.Rows) error {
+		err := pgxscan.ScanAll(data, rows)
+		dst.finish(item)
+		return err
+	})
+
+	return dst
+}
+
+// Exec queues a query without result, such as INSERT, UPDATE or DELETE.
+// The number of affected rows is stored into affected if it is not nil.
+//
+// Parameters:
+//   - model: The name of the model being changed, used for logging.
+//   - query: The SQL query string to be executed in the database.
+//   - affected: A pointer to store the number of affected rows, or nil.
+//   - args: Optional arguments bound to the $1..$n placeholders of the query.
+//
+// Returns:
+//   - The Batch itself, to allow chaining.
+func (dst *Batch) Exec(model string, query string, affected *uint, args ...any) *Batch {
+	item := dst.add(model, "Exec", "\033[34m", query, args)
+	dst.batch.Queue(query, args...).Exec(func(ct pgconn.CommandTag) error {
+		if affected != nil {
+			*affected = uint(ct.RowsAffected())
+		}
+		dst.finish(item)
+		return nil
+	})
+
+	return dst
+}
+
+// Len returns the number of queued statements.
+func (dst *Batch) Len() int {
+	return len(dst.items)
+}
+
+// Send sends all queued statements to PostgreSQL in a single round trip and processes their results.
+// Statements are executed in the order they were queued. Processing stops at the first failed statement.
+// When sending through the connection pool, the statements are executed in an implicit transaction.
+// The whole batch and each processed statement are logged once the batch is completed.
+//
+// Parameters:
+//   - ctx: The context for the operation, used for cancellation and timeout.
+//
+// Returns:
+//   - An error of the first failed statement, or nil if all statements succeed.
+func (dst *Batch) Send(ctx context.Context) error {
+	if len(dst.items) == 0 {
+		return nil
+	}
+
+	start := time.Now()
+	dst.last = start
+
+	err := dst.q.SendBatch(ctx, &dst.batch).Close()
+	dst.client.Debug(ctx, "\033[1m\033[36mPG BATCH (%.2f ms)\033[0m \033[1m\033[35m%d statements\033[0m", float64(time.Since(start))/1000000, len(dst.items))
+	for _, item := range dst.items {
+		if item.done {
+			dst.client.Debug(ctx, "\033[1m\033[36mPG %s %s (%.2f ms)\033[1m %s%s\033[0m%s", item.model, item.action, float64(item.elapsed)/1000000, item.color, database.OneLine(item.query), argsToStr(item.args))
+		}
+	}
+	if err != nil {
+		dst.client.Error(ctx, err)
+		return wrapError(err)
+	}
+
+	return nil
+}
+
+// add registers a statement for logging.
+func (dst *Batch) add(model, action, color, query string, args []any) *batchItem {
+	item := &batchItem{model: model, action: action, color: color, query: query, args: args}
+	dst.items = append(dst.items, item)
+
+	return item
+}
+
+// finish marks a statement as processed and records the time taken since the previous result.
+func (dst *Batch) finish(item *batchItem) {
+	now := time.Now()
+	item.elapsed = now.Sub(dst.last)
+	item.done = true
+	dst.last = now
+}
@@ -0,0 +1,150 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/ra-company/database"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// CopyFrom loads rows into a table using the PostgreSQL COPY protocol.
+// It is much faster than INSERT statements for bulk loads.
+// Rows can be provided as:
+//   - a slice of structs or pointers to structs, mapped to columns via `db` tags.
+//     If columns is empty, all tagged fields are copied.
+//   - a [][]any with values in the order of columns.
+//   - a pgx.CopyFromSource.
+//
+// It logs the time taken, the number of copied rows and the throughput for debugging purposes.
+//
+// Parameters:
+//   - ctx: The context for the operation, used for cancellation and timeout.
+//   - model: The name of the model being copied, used for logging.
+//   - table: The name of the table, optionally qualified with a schema name ("schema.table").
+//   - columns: The names of the columns to copy.
+//   - rows: The rows to copy.
+//
+// Returns:
+//   - The number of copied rows.
+//   - An error if the operation fails, or nil if it succeeds.
+func (dst *PostgresClient) CopyFrom(ctx context.Context, model string, table string, columns []string, rows any) (int64, error) {
+	return dst.copyFrom(ctx, dst.client, model, table, columns, rows)
+}
+
+// CopyFrom loads rows into a table inside the transaction using the PostgreSQL COPY protocol.
+// It works the same way as PostgresClient.CopyFrom, but the rows are copied within the transaction.
+//
+// Parameters:
+//   - ctx: The context for the operation, used for cancellation and timeout.
+//   - model: The name of the model being copied, used for logging.
+//   - table: The name of the table, optionally qualified with a schema name ("schema.table").
+//   - columns: The names of the columns to copy.
+//   - rows: The rows to copy.
+//
+// Returns:
+//   - The number of copied rows.
+//   - An error if the operation fails, or nil if it succeeds.
+func (dst *PostgresTx) CopyFrom(ctx context.Context, model string, table string, columns []string, rows any) (int64, error) {
+	return dst.client.copyFrom(ctx, dst.tx, model, table, columns, rows)
+}
+
+// copyFrom copies rows into a table on the given querier and logs the row count and throughput.
+func (dst *PostgresClient) copyFrom(ctx context.Context, q querier, model string, table string, columns []string, rows any) (int64, error) {
+	src, columns, err := copySource(rows, columns)
+	if err != nil {
+		return 0, err
+	}
+
+	start := time.Now()
+
+	n, err := q.CopyFrom(ctx, pgx.Identifier(strings.Split(table, ".")), columns, src)
+	elapsed := time.Since(start)
+	dst.Debug(ctx, "\033[1m\033[36mPG %s Copy (%.2f ms, %d rows, %.0f rows/s)\033[1m \033[32mCOPY %s (%s) FROM STDIN\033[0m", model, float64(elapsed)/1000000, n, float64(n)/elapsed.Seconds(), table, strings.Join(columns, ","))
+	if err != nil {
+		dst.Error(ctx, err)
+		return n, wrapError(err)
+	}
+
+	return n, nil
+}
+
+// copySource converts the rows passed to CopyFrom into a pgx.CopyFromSource.
+// For slices of structs, the columns are resolved via `db` tags and default to all tagged fields.
+//
+// Parameters:
+//   - rows: The rows to copy.
+//   - columns: The names of the columns to copy.
+//
+// Returns:
+//   - The source of rows for pgx.
+//   - The names of the columns to copy.
+//   - An error matching database.ErrorIncorrectParameters if the rows cannot be copied.
+func copySource(rows any, columns []string) (pgx.CopyFromSource, []string, error) {
+	switch v := rows.(type) {
+	case pgx.CopyFromSource:
+		if len(columns) == 0 {
+			return nil, nil, fmt.Errorf("%w: columns are required for a row source", database.ErrorIncorrectParameters)
+		}
+		return v, columns, nil
+	case [][]any:
+		if len(columns) == 0 {
+			return nil, nil, fmt.Errorf("%w: columns are required for a slice of values", database.ErrorIncorrectParameters)
+		}
+		return pgx.CopyFromRows(v), columns, nil
+	}
+
+	value := reflect.ValueOf(rows)
+	if value.Kind() != reflect.Slice {
+		return nil, nil, fmt.Errorf("%w: unsupported rows type %T", database.ErrorIncorrectParameters, rows)
+	}
+
+	elem := value.Type().Elem()
+	pointer := elem.Kind() == reflect.Pointer
+	if pointer {
+		elem = elem.Elem()
+	}
+	if elem.Kind() != reflect.Struct {
+		return nil, nil, fmt.Errorf("%w: unsupported rows type %T", database.ErrorIncorrectParameters, rows)
+	}
+
+	fields := map[string][]int{}
+	tagged := []string{}
+	for _, field := range dbFields(elem) {
+		fields[field.Column] = field.Index
+		tagged = append(tagged, field.Column)
+	}
+
+	if len(columns) == 0 {
+		columns = tagged
+	}
+
+	indexes := make([][]int, len(columns))
+	for i, column := range columns {
+		index, ok := fields[column]
+		if !ok {
+			return nil, nil, fmt.Errorf("%w: column %q is not mapped in %s", database.ErrorIncorrectParameters, column, elem)
+		}
+		indexes[i] = index
+	}
+
+	return pgx.CopyFromSlice(value.Len(), func(i int) ([]any, error) {
+		row := value.Index(i)
+		if pointer {
+			if row.IsNil() {
+				return nil, fmt.Errorf("%w: row %d is nil", database.ErrorIncorrectParameters, i)
+			}
+			row = row.Elem()
+		}
+
+		values := make([]any, len(indexes))
+		for j, index := range indexes {
+			values[j] = row.FieldByIndex(index).Interface()
+		}
+		return values, nil
+	}), columns, nil
+}
@@ -0,0 +1,86 @@
+package postgres
+
+import (
+	"errors"
+
+	"github.com/ra-company/database"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// Error is a PostgreSQL error classified into one of the database.Error* sentinels.
+// It wraps both the sentinel and the original error, so errors.Is works with the sentinel
+// and errors.As still works with *pgconn.PgError.
+// Details of the PostgreSQL error are copied to the structure for convenience.
+type Error struct {
+	Kind       error  // Kind: is the database.Error* sentinel the error is classified as.
+	Code       string // Code: is the SQLSTATE code of the error, empty if the error does not come from the server.
+	Constraint string // Constraint: is the name of the violated constraint, if any.
+	Table      string // Table: is the name of the table the error relates to, if any.
+	Column     string // Column: is the name of the column the error relates to, if any.
+	Err        error  // Err: is the original error returned by pgx.
+}
+
+// Error returns the message of the original error.
+func (dst *Error) Error() string {
+	return dst.Err.Error()
+}
+
+// Unwrap returns the sentinel and the original error, so that both can be matched with errors.Is and errors.As.
+func (dst *Error) Unwrap() []error {
+	return []error{dst.Kind, dst.Err}
+}
+
+// wrapError classifies an error returned by pgx and wraps it into Error.
+// pgx.ErrNoRows is classified as database.ErrorNotFound.
+// Integrity constraint violations are classified as database.ErrorUniqueViolation, database.ErrorForeignKeyViolation,
+// database.ErrorCheckViolation or database.ErrorNotNullViolation, and other server errors as database.ErrorDatabaseError.
+// Other errors, such as context cancellation, and already wrapped errors are returned as is.
+//
+// Parameters:
+//   - err: The error returned by pgx.
+//
+// Returns:
+//   - The wrapped error, or nil if err is nil.
+func wrapError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var wrapped *Error
+	if errors.As(err, &wrapped) {
+		return err
+	}
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return &Error{Kind: database.ErrorNotFound, Err: err}
+	}
+
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return err
+	}
+
+	res := &Error{
+		Kind:       database.ErrorDatabaseError,
+		Code:       pgErr.Code,
+		Constraint: pgErr.ConstraintName,
+		Table:      pgErr.TableName,
+		Column:     pgErr.ColumnName,
+		Err:        err,
+	}
+
+	switch pgErr.Code {
+	case "23505":
+		res.Kind = database.ErrorUniqueViolation
+	case "23503":
+		res.Kind = database.ErrorForeignKeyViolation
+	case "23514":
+		res.Kind = database.ErrorCheckViolation
+	case "23502":
+		res.Kind = database.ErrorNotNullViolation
+	}
+
+	return res
+}
@@ -1,9 +1,14 @@
 package postgres
 
 import (
+	"bytes"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"reflect"
+	"regexp"
 	"slices"
+	"strconv"
 	"strings"
 	"time"
 
@@ -11,13 +16,29 @@ import (
 	"github.com/ra-company/database"
 )
 
+var (
+	placeholderRe = regexp.MustCompile(`\$\d+`)    // placeholderRe: matches the $n placeholders of a query.
+	timeType      = reflect.TypeFor[time.Time]()   // timeType: is the type of time.Time fields.
+	uuidType      = reflect.TypeFor[uuid.UUID]()   // uuidType: is the type of uuid.UUID fields.
+	uuidSliceType = reflect.TypeFor[[]uuid.UUID]() // uuidSliceType: is the type of []uuid.UUID fields.
+	bytesType     = reflect.TypeFor[[]byte]()      // bytesType: is the type of []byte fields.
+)
+
 // FieldValue is a structure to build a PostgreSQL update query.
 // It collects field names and their new values, and can generate an SQL update statement.
 // It also tracks the last update time.
 // The structure is designed to be used in a context where changes to fields need to be recorded
+//
+// By default, the values are escaped and inlined into the query as literals.
+// If Parameterized is true, the values are collected as typed Go values in Args and the query
+// refers to them with $n placeholders. Use UpdateQueryArgs, CustomUpdateQueryArgs, InsertQueryArgs
+// and UpsertQueryArgs to get the query together with its arguments in this mode; UpdateQuery, CustomUpdateQuery,
+// InsertQuery and UpsertQuery panic in this mode.
 type FieldValue struct {
-	Fields []string
-	Values []string
+	Fields        []string
+	Values        []string
+	Args          []any // Args: are the values bound to the $n placeholders of Values in the parameterized mode.
+	Parameterized bool  // Parameterized: if true, values are collected in Args instead of being inlined into the query.
 }
 
 // String compares two string values and adds the new value to the Fields and Values slices if they differ.
@@ -39,8 +60,7 @@ func (dst *FieldValue) String(was, is, field string) {
 //   - is: the new string value to be added.
 //   - field: the name of the field being added, which will be appended to the Fields slice.
 func (dst *FieldValue) AddString(is, field string) {
-	dst.Fields = append(dst.Fields, field)
-	dst.Values = append(dst.Values, fmt.Sprintf("'%s'", database.ToStr(is)))
+	dst.add(field, fmt.Sprintf("'%s'", database.ToStr(is)), is, "")
 }
 
 // UInt8 compares two uint8 values and adds the new value to the Fields and Values slices if they differ.
@@ -140,8 +160,7 @@ func (dst *FieldValue) Int64(was, is int64, field string) {
 //   - is: the new uint64 value to be added.
 //   - field: the name of the field being added, which will be appended to the Fields slice.
 func (dst *FieldValue) AddInt64(is int64, field string) {
-	dst.Fields = append(dst.Fields, field)
-	dst.Values = append(dst.Values, fmt.Sprintf("%d", is))
+	dst.add(field, fmt.Sprintf("%d", is), is, "")
 }
 
 // AddUInt64 adds a new uint64 value to the Fields and Values slices.
@@ -151,8 +170,7 @@ func (dst *FieldValue) AddInt64(is int64, field string) {
 //   - is: the new uint64 value to be added.
 //   - field: the name of the field being added, which will be appended to the Fields slice.
 func (dst *FieldValue) AddUInt64(is uint64, field string) {
-	dst.Fields = append(dst.Fields, field)
-	dst.Values = append(dst.Values, fmt.Sprintf("%d", is))
+	dst.add(field, fmt.Sprintf("%d", is), is, "")
 }
 
 // Bool compares two boolean values and adds the new value to the Fields and Values slices if they differ.
@@ -174,8 +192,7 @@ func (dst *FieldValue) Bool(was, is bool, field string) {
 //   - is: the new boolean value to be added.
 //   - field: the name of the field being added, which will be appended to the Fields slice.
 func (dst *FieldValue) AddBool(is bool, field string) {
-	dst.Fields = append(dst.Fields, field)
-	dst.Values = append(dst.Values, fmt.Sprintf("%t", is))
+	dst.add(field, fmt.Sprintf("%t", is), is, "")
 }
 
 // Time compares two time.Time values and adds the new value to the Fields and Values slices if they differ.
@@ -196,8 +213,7 @@ func (dst *FieldValue) Time(was, is time.Time, field string) {
 //   - is: the new time.Time value to be added.
 //   - field: the name of the field being added, which will be appended to the Fields slice.
 func (dst *FieldValue) AddTime(is time.Time, field string) {
-	dst.Fields = append(dst.Fields, field)
-	dst.Values = append(dst.Values, fmt.Sprintf("'%s'", is.UTC().Format(time.RFC3339Nano)))
+	dst.add(field, fmt.Sprintf("'%s'", is.UTC().Format(time.RFC3339Nano)), is.UTC(), "")
 }
 
 // Date compares the date components of two time.Time values and adds the new value to the Fields and Values slices if they differ.
@@ -219,8 +235,8 @@ func (dst *FieldValue) Date(was, is time.Time, field string) {
 //   - is: the new time.Time value to be added.
 //   - field: the name of the field being added, which will be appended to the Fields slice.
 func (dst *FieldValue) AddDate(is time.Time, field string) {
-	dst.Fields = append(dst.Fields, field)
-	dst.Values = append(dst.Values, fmt.Sprintf("'%s'", is.UTC().Format("2006-01-02")))
+	date := is.UTC()
+	dst.add(field, fmt.Sprintf("'%s'", date.Format("2006-01-02")), time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.UTC), "")
 }
 
 // UUID compares two UUID values and adds the new value to the Fields and Values slices if they differ.
@@ -244,11 +260,10 @@ func (dst *FieldValue) UUID(was, is uuid.UUID, field string, nullable bool) {
 //   - field: the name of the field being added, which will be appended to the Fields slice.
 //   - nullable: if true, allows the UUID to be nil (NULL) in the database.
 func (dst *FieldValue) AddUUID(is uuid.UUID, field string, nullable bool) {
-	dst.Fields = append(dst.Fields, field)
 	if is == uuid.Nil && nullable {
-		dst.Values = append(dst.Values, "NULL")
+		dst.add(field, "NULL", nil, "")
 	} else {
-		dst.Values = append(dst.Values, fmt.Sprintf("'%s'", is.String()))
+		dst.add(field, fmt.Sprintf("'%s'", is.String()), is, "")
 	}
 }
 
@@ -264,11 +279,36 @@ func (dst *FieldValue) AddJSON(is any, field string) error {
 	if err != nil {
 		return err
 	}
-	dst.Fields = append(dst.Fields, field)
-	dst.Values = append(dst.Values, fmt.Sprintf("'%s'", database.ToStr(string(data))))
+	dst.add(field, fmt.Sprintf("'%s'", database.ToStr(string(data))), string(data), "")
 	return nil
 }
 
+// Bytes compares two byte slices and adds the new value to the Fields and Values slices if they differ.
+//
+// Parameters:
+//   - was: the original byte slice.
+//   - is: the new byte slice.
+//   - field: the name of the field being compared, which will be added to the Fields slice.
+func (dst *FieldValue) Bytes(was, is []byte, field string) {
+	if !bytes.Equal(was, is) {
+		dst.AddBytes(is, field)
+	}
+}
+
+// AddBytes adds a new byte slice to the Fields and Values slices.
+// It formats the value as a PostgreSQL bytea literal in hex, or NULL if the slice is nil.
+//
+// Parameters:
+//   - is: the new byte slice to be added.
+//   - field: the name of the field being added, which will be appended to the Fields slice.
+func (dst *FieldValue) AddBytes(is []byte, field string) {
+	if is == nil {
+		dst.add(field, "NULL", nil, "")
+	} else {
+		dst.add(field, fmt.Sprintf("'\\x%s'::BYTEA", hex.EncodeToString(is)), is, "::BYTEA")
+	}
+}
+
 // Int8Slice compares two slices of int8 values and adds the new slice to the Fields and Values slices if they differ.
 //
 // Parameters:
@@ -288,8 +328,11 @@ func (dst *FieldValue) Int8Slice(was, is []int8, field string) {
 //   - is: the new slice of int8 values to be added.
 //   - field: the name of the field being added, which will be appended to the Fields slice.
 func (dst *FieldValue) AddInt8Slice(is []int8, field string) {
-	dst.Fields = append(dst.Fields, field)
-	dst.Values = append(dst.Values, fmt.Sprintf("ARRAY%s::SMALLINT[]", database.ArrayToString(is)))
+	values := make([]int16, len(is))
+	for i, v := range is {
+		values[i] = int16(v)
+	}
+	dst.add(field, fmt.Sprintf("ARRAY%s::SMALLINT[]", database.ArrayToString(is)), values, "::SMALLINT[]")
 }
 
 // Int16Slice compares two slices of int16 values and adds the new slice to the Fields and Values slices if they differ.
@@ -311,8 +354,7 @@ func (dst *FieldValue) Int16Slice(was, is []int16, field string) {
 //   - is: the new slice of int16 values to be added.
 //   - field: the name of the field being added, which will be appended to the
 func (dst *FieldValue) AddInt16Slice(is []int16, field string) {
-	dst.Fields = append(dst.Fields, field)
-	dst.Values = append(dst.Values, fmt.Sprintf("ARRAY%s::SMALLINT[]", database.ArrayToString(is)))
+	dst.add(field, fmt.Sprintf("ARRAY%s::SMALLINT[]", database.ArrayToString(is)), is, "::SMALLINT[]")
 }
 
 // Int32Slice compares two slices of int32 values and adds the new slice to the Fields and Values slices if they differ.
@@ -333,8 +375,7 @@ func (dst *FieldValue) Int32Slice(was, is []int32, field string) {
 //   - is: the new slice of int32 values to be added.
 //   - field: the name of the field being added, which will be appended to the
 func (dst *FieldValue) AddInt32Slice(is []int32, field string) {
-	dst.Fields = append(dst.Fields, field)
-	dst.Values = append(dst.Values, fmt.Sprintf("ARRAY%s::INTEGER[]", database.ArrayToString(is)))
+	dst.add(field, fmt.Sprintf("ARRAY%s::INTEGER[]", database.ArrayToString(is)), is, "::INTEGER[]")
 }
 
 // Int64Slice compares two slices of int64 values and adds the new slice to the Fields and Values slices if they differ.
@@ -356,8 +397,7 @@ func (dst *FieldValue) Int64Slice(was, is []int64, field string) {
 //   - is: the new slice of int64 values to be added.
 //   - field: the name of the field being added, which will be appended to the Fields slice.
 func (dst *FieldValue) AddInt64Slice(is []int64, field string) {
-	dst.Fields = append(dst.Fields, field)
-	dst.Values = append(dst.Values, fmt.Sprintf("ARRAY%s::BIGINT[]", database.ArrayToString(is)))
+	dst.add(field, fmt.Sprintf("ARRAY%s::BIGINT[]", database.ArrayToString(is)), is, "::BIGINT[]")
 }
 
 // StringSlice compares two slices of string values and adds the new slice to the Fields and Values slices if they differ.
@@ -379,8 +419,7 @@ func (dst *FieldValue) StringSlice(was, is []string, field string) {
 //   - is: the new slice of string values to be added.
 //   - field: the name of the field being added, which will be appended to the Fields slice.
 func (dst *FieldValue) AddStringSlice(is []string, field string) {
-	dst.Fields = append(dst.Fields, field)
-	dst.Values = append(dst.Values, fmt.Sprintf("ARRAY%s::VARCHAR[]", database.ArrayToString(is)))
+	dst.add(field, fmt.Sprintf("ARRAY%s::VARCHAR[]", database.ArrayToString(slices.Clone(is))), is, "::VARCHAR[]")
 }
 
 // UUIDSlice compares two slices of UUID values and adds the new slice to the Fields and Values slices if they differ.
@@ -402,13 +441,14 @@ func (dst *FieldValue) UUIDSlice(was, is []uuid.UUID, field string) {
 //   - is: the new slice of UUID values to be added.
 //   - field: the name of the field being added, which will be appended to the Fields slice.
 func (dst *FieldValue) AddUUIDSlice(is []uuid.UUID, field string) {
-	dst.Fields = append(dst.Fields, field)
-	dst.Values = append(dst.Values, fmt.Sprintf("ARRAY%s::UUID[]", database.ArrayToString(is)))
+	dst.add(field, fmt.Sprintf("ARRAY%s::UUID[]", database.ArrayToString(is)), is, "::UUID[]")
 }
 
 // UpdateQuery generates a PostgreSQL update query string using the collected fields and values.
 // It returns the query string and the current time as the update timestamp.
 // If no fields are set, it returns an empty string and the zero time value.
+// It panics in the parameterized mode, because the query cannot be executed without its arguments;
+// use UpdateQueryArgs instead.
 //
 // Parameters:
 //   - table: the name of the table to update.
@@ -418,6 +458,8 @@ func (dst *FieldValue) AddUUIDSlice(is []uuid.UUID, field string) {
 //   - string: the generated SQL update query.
 //   - time.Time: the current time in UTC, representing the update timestamp.
 func (dst *FieldValue) UpdateQuery(table string, id any) (string, time.Time) {
+	dst.literalOnly("UpdateQuery")
+
 	switch v := id.(type) {
 	case uint, uint8, uint16, uint32, uint64, int, int8, int16, int32, int64:
 		return dst.CustomUpdateQuery(table, fmt.Sprintf("id = %d", v))
@@ -431,6 +473,8 @@ func (dst *FieldValue) UpdateQuery(table string, id any) (string, time.Time) {
 // CustomUpdateQuery generates a PostgreSQL update query string using the collected fields and values.
 // It returns the query string and the current time as the update timestamp.
 // If no fields are set, it returns an empty string and the zero time value.
+// It panics in the parameterized mode, because the query cannot be executed without its arguments;
+// use CustomUpdateQueryArgs instead.
 //
 // Parameters:
 //   - table: the name of the table to update.
@@ -440,13 +484,439 @@ func (dst *FieldValue) UpdateQuery(table string, id any) (string, time.Time) {
 //   - string: the generated SQL update query.
 //   - time.Time: the current time in UTC, representing the update timestamp.
 func (dst *FieldValue) CustomUpdateQuery(table, where string) (string, time.Time) {
+	dst.literalOnly("CustomUpdateQuery")
+
+	query, _, update := dst.CustomUpdateQueryArgs(table, where)
+	return query, update
+}
+
+// UpdateQueryArgs generates a PostgreSQL update query string and its arguments using the collected fields and values.
+// The identifier of the record is always bound as an argument, so the query is ready for PostgresClient.Update.
+// If no fields are set, it returns an empty string, no arguments and the zero time value.
+//
+// Parameters:
+//   - table: the name of the table to update.
+//   - id: the identifier of the record to update.
+//
+// Returns:
+//   - string: the generated SQL update query.
+//   - []any: the arguments bound to the $n placeholders of the query.
+//   - time.Time: the current time in UTC, representing the update timestamp.
+func (dst *FieldValue) UpdateQueryArgs(table string, id any) (string, []any, time.Time) {
+	return dst.CustomUpdateQueryArgs(table, "id = $1", id)
+}
+
+// CustomUpdateQueryArgs generates a PostgreSQL update query string and its arguments using the collected fields and values.
+// The WHERE clause uses its own $1..$n placeholders for whereArgs; they are renumbered to follow the arguments of the values.
+// If no fields are set, it returns an empty string, no arguments and the zero time value.
+//
+// Parameters:
+//   - table: the name of the table to update.
+//   - where: the WHERE clause to specify which record(s) to update.
+//   - whereArgs: the arguments bound to the placeholders of the WHERE clause.
+//
+// Returns:
+//   - string: the generated SQL update query.
+//   - []any: the arguments bound to the $n placeholders of the query.
+//   - time.Time: the current time in UTC, representing the update timestamp.
+func (dst *FieldValue) CustomUpdateQueryArgs(table, where string, whereArgs ...any) (string, []any, time.Time) {
 	if len(dst.Fields) == 0 {
-		return "", time.Time{}
+		return "", nil, time.Time{}
 	}
 
+	fields, values, args, update := dst.withUpdatedAt()
+	where = renumberPlaceholders(where, len(args))
+	args = append(args, whereArgs...)
+
+	return fmt.Sprintf("UPDATE %s SET (%s) = (%s) WHERE %s", table, strings.Join(fields, ","), strings.Join(values, ","), where), args, update
+}
+
+// InsertQuery generates a PostgreSQL insert query string using the collected fields and values.
+// The updated_at field is added with the current time, unless it was set explicitly.
+// The query can be passed to PostgresClient.Insert or PostgresClient.InsertUUID, which append "RETURNING id".
+// If no fields are set, it returns an empty string and the zero time value.
+// It panics in the parameterized mode, because the query cannot be executed without its arguments;
+// use InsertQueryArgs instead.
+//
+// Parameters:
+//   - table: the name of the table to insert into.
+//
+// Returns:
+//   - string: the generated SQL insert query.
+//   - time.Time: the current time in UTC, representing the update timestamp.
+func (dst *FieldValue) InsertQuery(table string) (string, time.Time) {
+	dst.literalOnly("InsertQuery")
+
+	query, _, update := dst.InsertQueryArgs(table)
+	return query, update
+}
+
+// InsertQueryArgs generates a PostgreSQL insert query string and its arguments using the collected fields and values.
+// It works the same way as InsertQuery, and also returns the arguments collected in the parameterized mode.
+//
+// Parameters:
+//   - table: the name of the table to insert into.
+//
+// Returns:
+//   - string: the generated SQL insert query.
+//   - []any: the arguments bound to the $n placeholders of the query.
+//   - time.Time: the current time in UTC, representing the update timestamp.
+func (dst *FieldValue) InsertQueryArgs(table string) (string, []any, time.Time) {
+	if len(dst.Fields) == 0 {
+		return "", nil, time.Time{}
+	}
+
+	fields, values, args, update := dst.withUpdatedAt()
+
+	return fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)", table, strings.Join(fields, ","), strings.Join(values, ",")), args, update
+}
+
+// UpsertQuery generates a PostgreSQL insert query string with an ON CONFLICT DO UPDATE clause
+// using the collected fields and values. When a row with the same conflict columns exists,
+// the update columns are set to the new values and updated_at is set to the current time.
+// If updateColumns is empty, all collected fields except the conflict columns are updated.
+// If conflictColumns is empty, the clause is ON CONFLICT DO NOTHING, since DO UPDATE requires a conflict target,
+// and no row is returned on conflict; it panics if updateColumns is set without conflictColumns,
+// because the columns could not be updated.
+// The query can be passed to PostgresClient.Insert or PostgresClient.InsertUUID, which append "RETURNING id",
+// to get the identifier of the inserted or updated row.
+// If no fields are set, it returns an empty string and the zero time value.
+// It panics in the parameterized mode, because the query cannot be executed without its arguments;
+// use UpsertQueryArgs instead.
+//
+// Parameters:
+//   - table: the name of the table to insert into.
+//   - conflictColumns: the columns of the unique index or constraint that detects the conflict.
+//   - updateColumns: the columns to update on conflict.
+//
+// Returns:
+//   - string: the generated SQL upsert query.
+//   - time.Time: the current time in UTC, representing the update timestamp.
+func (dst *FieldValue) UpsertQuery(table string, conflictColumns, updateColumns []string) (string, time.Time) {
+	dst.literalOnly("UpsertQuery")
+
+	query, _, update := dst.UpsertQueryArgs(table, conflictColumns, updateColumns)
+	return query, update
+}
+
+// UpsertQueryArgs generates a PostgreSQL upsert query string and its arguments using the collected fields and values.
+// It works the same way as UpsertQuery, and also returns the arguments collected in the parameterized mode.
+//
+// Parameters:
+//   - table: the name of the table to insert into.
+//   - conflictColumns: the columns of the unique index or constraint that detects the conflict.
+//   - updateColumns: the columns to update on conflict.
+//
+// Returns:
+//   - string: the generated SQL upsert query.
+//   - []any: the arguments bound to the $n placeholders of the query.
+//   - time.Time: the current time in UTC, representing the update timestamp.
+func (dst *FieldValue) UpsertQueryArgs(table string, conflictColumns, updateColumns []string) (string, []any, time.Time) {
+	if len(conflictColumns) == 0 && len(updateColumns) > 0 {
+		panic(fmt.Sprintf("postgres: FieldValue.UpsertQuery cannot update %s without conflict columns", strings.Join(updateColumns, ",")))
+	}
+
+	query, args, update := dst.InsertQueryArgs(table)
+	if query == "" {
+		return "", nil, update
+	}
+
+	return query + dst.onConflict(conflictColumns, updateColumns), args, update
+}
+
+// onConflict generates the ON CONFLICT DO UPDATE clause of an upsert query,
+// or ON CONFLICT DO NOTHING if there are no conflict columns.
+func (dst *FieldValue) onConflict(conflictColumns, updateColumns []string) string {
+	if len(conflictColumns) == 0 {
+		return " ON CONFLICT DO NOTHING"
+	}
+
+	if len(updateColumns) == 0 {
+		for _, field := range dst.Fields {
+			if !slices.Contains(conflictColumns, field) {
+				updateColumns = append(updateColumns, field)
+			}
+		}
+	}
+	if !slices.Contains(updateColumns, "updated_at") {
+		updateColumns = append(slices.Clone(updateColumns), "updated_at")
+	}
+
+	set := make([]string, len(updateColumns))
+	for i, column := range updateColumns {
+		set[i] = fmt.Sprintf("%s = EXCLUDED.%s", column, column)
+	}
+
+	return fmt.Sprintf(" ON CONFLICT (%s) DO UPDATE SET %s", strings.Join(conflictColumns, ","), strings.Join(set, ","))
+}
+
+// literalOnly panics if a query builder returning no arguments is called in the parameterized mode,
+// so the values are not silently lost.
+func (dst *FieldValue) literalOnly(method string) {
+	if dst.Parameterized {
+		panic(fmt.Sprintf("postgres: FieldValue.%s cannot be used in the parameterized mode, use %sArgs instead", method, method))
+	}
+}
+
+// withUpdatedAt returns the collected fields, values and arguments with updated_at set to the current time,
+// unless it was set explicitly.
+func (dst *FieldValue) withUpdatedAt() ([]string, []string, []any, time.Time) {
 	update := time.Now().UTC()
-	fields := fmt.Sprintf("%s,updated_at", strings.Join(dst.Fields, ","))
-	values := fmt.Sprintf("%s,'%s'", strings.Join(dst.Values, ","), update.Format(time.RFC3339Nano))
+	fields := slices.Clone(dst.Fields)
+	values := slices.Clone(dst.Values)
+	args := slices.Clone(dst.Args)
+	if slices.Contains(fields, "updated_at") {
+		return fields, values, args, update
+	}
+
+	fields = append(fields, "updated_at")
+	if dst.Parameterized {
+		args = append(args, update)
+		values = append(values, fmt.Sprintf("$%d", len(args)))
+	} else {
+		values = append(values, fmt.Sprintf("'%s'", update.Format(time.RFC3339Nano)))
+	}
+
+	return fields, values, args, update
+}
+
+// add appends a field and its value, inlined as a literal or bound as an argument in the parameterized mode.
+// The cast is appended to the placeholder of the argument, such as "::BIGINT[]" for arrays.
+func (dst *FieldValue) add(field, literal string, arg any, cast string) {
+	dst.Fields = append(dst.Fields, field)
+	if !dst.Parameterized {
+		dst.Values = append(dst.Values, literal)
+		return
+	}
+
+	dst.Args = append(dst.Args, arg)
+	dst.Values = append(dst.Values, fmt.Sprintf("$%d%s", len(dst.Args), cast))
+}
+
+// renumberPlaceholders shifts the $n placeholders of a query fragment by offset.
+func renumberPlaceholders(query string, offset int) string {
+	if offset == 0 {
+		return query
+	}
+
+	return placeholderRe.ReplaceAllStringFunc(query, func(placeholder string) string {
+		n, _ := strconv.Atoi(placeholder[1:])
+		return fmt.Sprintf("$%d", n+offset)
+	})
+}
+
+// Diff compares two structs of the same type field by field and adds the changed fields to the Fields and Values slices.
+// The fields are mapped to columns with `db` tags, like when scanning, and compared with the typed comparators
+// (String, Int*, UInt*, Bool, Time, Date, UUID, Bytes and the slice methods). Fields of other types, such as
+// structs and maps, are compared and stored as JSON. Pointer fields are dereferenced, and a nil pointer is stored as NULL.
+// The following tag options are supported:
+//   - omitdiff: the field is skipped, such as `db:"id,omitdiff"`.
+//   - date: a time.Time field is compared and stored as a date.
+//   - nullable: a zero UUID, or a nil map or slice stored as JSON, is stored as NULL.
+//
+// Parameters:
+//   - was: the original struct, or a pointer to it.
+//   - is: the new struct, or a pointer to it, of the same type as was.
+//
+// Returns:
+//   - error: an error matching database.ErrorIncorrectParameters if the values are not structs of the same type,
+//     or an error if a value cannot be marshaled into JSON.
+func (dst *FieldValue) Diff(was, is any) error {
+	w, i := reflect.Indirect(reflect.ValueOf(was)), reflect.Indirect(reflect.ValueOf(is))
+	if !w.IsValid() || !i.IsValid() || w.Kind() != reflect.Struct || w.Type() != i.Type() {
+		return fmt.Errorf("%w: Diff requires two structs of the same type, got %T and %T", database.ErrorIncorrectParameters, was, is)
+	}
+
+	for _, field := range dbFields(i.Type()) {
+		if field.HasOption("omitdiff") {
+			continue
+		}
+
+		if err := dst.diffField(w.FieldByIndex(field.Index), i.FieldByIndex(field.Index), field); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// diffField compares the values of a field with the typed comparator of its type.
+func (dst *FieldValue) diffField(w, i reflect.Value, field dbField) error {
+	column := field.Column
+
+	if i.Kind() == reflect.Pointer {
+		switch {
+		case w.IsNil() && i.IsNil():
+			return nil
+		case i.IsNil():
+			dst.add(column, "NULL", nil, "")
+			return nil
+		case w.IsNil():
+			return dst.addField(i.Elem(), field)
+		}
+		return dst.diffField(w.Elem(), i.Elem(), field)
+	}
+
+	switch {
+	case i.Type() == timeType:
+		if field.HasOption("date") {
+			dst.Date(w.Interface().(time.Time), i.Interface().(time.Time), column)
+		} else {
+			dst.Time(w.Interface().(time.Time), i.Interface().(time.Time), column)
+		}
+		return nil
+	case i.Type() == uuidType:
+		dst.UUID(w.Interface().(uuid.UUID), i.Interface().(uuid.UUID), column, field.HasOption("nullable"))
+		return nil
+	case i.Type().ConvertibleTo(uuidSliceType) && i.Kind() == reflect.Slice:
+		dst.UUIDSlice(w.Convert(uuidSliceType).Interface().([]uuid.UUID), i.Convert(uuidSliceType).Interface().([]uuid.UUID), column)
+		return nil
+	case i.Type() == bytesType:
+		dst.Bytes(w.Bytes(), i.Bytes(), column)
+		return nil
+	}
+
+	switch i.Kind() {
+	case reflect.String:
+		dst.String(w.String(), i.String(), column)
+	case reflect.Bool:
+		dst.Bool(w.Bool(), i.Bool(), column)
+	case reflect.Int8:
+		dst.Int8(int8(w.Int()), int8(i.Int()), column)
+	case reflect.Int16:
+		dst.Int16(int16(w.Int()), int16(i.Int()), column)
+	case reflect.Int32:
+		dst.Int32(int32(w.Int()), int32(i.Int()), column)
+	case reflect.Int, reflect.Int64:
+		dst.Int64(w.Int(), i.Int(), column)
+	case reflect.Uint8:
+		dst.UInt8(uint8(w.Uint()), uint8(i.Uint()), column)
+	case reflect.Uint16:
+		dst.UInt16(uint16(w.Uint()), uint16(i.Uint()), column)
+	case reflect.Uint32:
+		dst.UInt32(uint32(w.Uint()), uint32(i.Uint()), column)
+	case reflect.Uint, reflect.Uint64:
+		dst.UInt64(w.Uint(), i.Uint(), column)
+	case reflect.Slice:
+		switch sliceKind(i.Type()) {
+		case reflect.Int8:
+			dst.Int8Slice(convertSlice[int8](w), convertSlice[int8](i), column)
+		case reflect.Int16:
+			dst.Int16Slice(convertSlice[int16](w), convertSlice[int16](i), column)
+		case reflect.Int32:
+			dst.Int32Slice(convertSlice[int32](w), convertSlice[int32](i), column)
+		case reflect.Int64:
+			dst.Int64Slice(convertSlice[int64](w), convertSlice[int64](i), column)
+		case reflect.String:
+			dst.StringSlice(convertSlice[string](w), convertSlice[string](i), column)
+		default:
+			return dst.diffJSON(w, i, field)
+		}
+	default:
+		return dst.diffJSON(w, i, field)
+	}
+
+	return nil
+}
+
+// addField adds the value of a field with the typed adder of its type, used when the original pointer is nil.
+func (dst *FieldValue) addField(i reflect.Value, field dbField) error {
+	column := field.Column
+
+	switch {
+	case i.Type() == timeType:
+		if field.HasOption("date") {
+			dst.AddDate(i.Interface().(time.Time), column)
+		} else {
+			dst.AddTime(i.Interface().(time.Time), column)
+		}
+		return nil
+	case i.Type() == uuidType:
+		dst.AddUUID(i.Interface().(uuid.UUID), column, field.HasOption("nullable"))
+		return nil
+	case i.Type().ConvertibleTo(uuidSliceType) && i.Kind() == reflect.Slice:
+		dst.AddUUIDSlice(i.Convert(uuidSliceType).Interface().([]uuid.UUID), column)
+		return nil
+	case i.Type() == bytesType:
+		dst.AddBytes(i.Bytes(), column)
+		return nil
+	}
+
+	switch i.Kind() {
+	case reflect.String:
+		dst.AddString(i.String(), column)
+	case reflect.Bool:
+		dst.AddBool(i.Bool(), column)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		dst.AddInt64(i.Int(), column)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		dst.AddUInt64(i.Uint(), column)
+	case reflect.Slice:
+		switch sliceKind(i.Type()) {
+		case reflect.Int8:
+			dst.AddInt8Slice(convertSlice[int8](i), column)
+		case reflect.Int16:
+			dst.AddInt16Slice(convertSlice[int16](i), column)
+		case reflect.Int32:
+			dst.AddInt32Slice(convertSlice[int32](i), column)
+		case reflect.Int64:
+			dst.AddInt64Slice(convertSlice[int64](i), column)
+		case reflect.String:
+			dst.AddStringSlice(convertSlice[string](i), column)
+		default:
+			return dst.addJSON(i, field)
+		}
+	default:
+		return dst.addJSON(i, field)
+	}
+
+	return nil
+}
+
+// diffJSON compares the JSON representations of the values of a field and adds the new one if they differ.
+func (dst *FieldValue) diffJSON(w, i reflect.Value, field dbField) error {
+	was, err := json.Marshal(w.Interface())
+	if err != nil {
+		return err
+	}
+	is, err := json.Marshal(i.Interface())
+	if err != nil {
+		return err
+	}
+
+	if string(was) != string(is) {
+		return dst.addJSON(i, field)
+	}
+
+	return nil
+}
+
+// addJSON adds the value of a field as JSON, or NULL for a nil map or slice of a nullable field.
+func (dst *FieldValue) addJSON(i reflect.Value, field dbField) error {
+	switch i.Kind() {
+	case reflect.Map, reflect.Slice, reflect.Interface:
+		if i.IsNil() && field.HasOption("nullable") {
+			dst.add(field.Column, "NULL", nil, "")
+			return nil
+		}
+	}
+
+	return dst.AddJSON(i.Interface(), field.Column)
+}
+
+// sliceKind returns the kind of the elements of a slice type convertible to a slice of a basic type,
+// or reflect.Invalid if the slice has to be stored as JSON.
+func sliceKind(t reflect.Type) reflect.Kind {
+	for _, basic := range []reflect.Type{reflect.TypeFor[[]int8](), reflect.TypeFor[[]int16](), reflect.TypeFor[[]int32](), reflect.TypeFor[[]int64](), reflect.TypeFor[[]string]()} {
+		if t.ConvertibleTo(basic) {
+			return basic.Elem().Kind()
+		}
+	}
+
+	return reflect.Invalid
+}
 
-	return fmt.Sprintf("UPDATE %s SET (%s) = (%s) WHERE %s", table, fields, values, where), update
+// convertSlice converts a slice value, possibly of a named slice type, to []T.
+func convertSlice[T any](v reflect.Value) []T {
+	return v.Convert(reflect.TypeFor[[]T]()).Interface().([]T)
 }
@@ -0,0 +1,165 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"iter"
+	"time"
+
+	"github.com/georgysavva/scany/v2/pgxscan"
+	"github.com/ra-company/database"
+
+	"github.com/jackc/pgx/v5"
+)
+
+var (
+	errStopIteration = errors.New("iteration stopped") // errStopIteration: is returned to selectEach when the consumer of QueryIter stops early.
+)
+
+// Handle is implemented by *PostgresClient and *PostgresTx.
+// It allows repository functions and the generic query helpers to work the same way
+// on the connection pool and inside a transaction.
+// On the connection pool, the generic query helpers use a read replica if one is connected.
+type Handle interface {
+	Select(ctx context.Context, model string, query string, data any, args ...any) error
+	SelectEach(ctx context.Context, model string, query string, data any, fn func() error, args ...any) error
+	Insert(ctx context.Context, model string, query string, args ...any) ([]uint, error)
+	Update(ctx context.Context, model string, query string, args ...any) (uint, error)
+	Delete(ctx context.Context, model string, query string, args ...any) (uint, error)
+	Count(ctx context.Context, model string, query string, args ...any) (uint64, error)
+	Exec(ctx context.Context, model string, query string, args ...any) error
+	WithTx(ctx context.Context, opts pgx.TxOptions, fn func(tx *PostgresTx) error) error
+
+	// read executes fn with the client used for logging and the querier used for read queries.
+	read(ctx context.Context, fn func(client *PostgresClient, q querier) error) error
+}
+
+// read executes fn with the client that started the transaction and the transaction itself.
+func (dst *PostgresTx) read(ctx context.Context, fn func(client *PostgresClient, q querier) error) error {
+	return fn(dst.client, dst.tx)
+}
+
+// Query selects rows from database and scans them into a slice of T.
+// T is usually a struct with `db` tags, but can also be a map or a primitive type for single-column queries.
+// It logs the time taken for the query execution and the query itself in the same format as PostgresClient.Select.
+//
+// Parameters:
+//   - ctx: The context for the operation, used for cancellation and timeout.
+//   - db: The PostgreSQL client or transaction to run the query on.
+//   - model: The name of the model being queried, used for logging.
+//   - query: The SQL query string to be executed in the database.
+//   - args: Optional arguments bound to the $1..$n placeholders of the query.
+//
+// Returns:
+//   - A slice of T with the selected rows, empty if no rows were found.
+//   - An error if the operation fails, or nil if it succeeds.
+func Query[T any](ctx context.Context, db Handle, model string, query string, args ...any) ([]T, error) {
+	res := []T{}
+	err := db.read(ctx, func(client *PostgresClient, q querier) error {
+		return client.selectRows(ctx, q, model, query, &res, args)
+	})
+
+	return res, err
+}
+
+// QueryOne selects a single row from database and scans it into T.
+// If the query returns no rows, the error matches database.ErrorNotFound.
+// If the query returns more than one row, an error is returned, so add LIMIT 1 to the query to select the first row only.
+// It logs the time taken for the query execution and the query itself in the same format as PostgresClient.Select.
+//
+// Parameters:
+//   - ctx: The context for the operation, used for cancellation and timeout.
+//   - db: The PostgreSQL client or transaction to run the query on.
+//   - model: The name of the model being queried, used for logging.
+//   - query: The SQL query string to be executed in the database.
+//   - args: Optional arguments bound to the $1..$n placeholders of the query.
+//
+// Returns:
+//   - The selected row.
+//   - An error if the operation fails, no rows were found or more than one row was found, or nil if it succeeds.
+func QueryOne[T any](ctx context.Context, db Handle, model string, query string, args ...any) (T, error) {
+	var res T
+	err := db.read(ctx, func(client *PostgresClient, q querier) error {
+		start := time.Now()
+
+		err := pgxscan.Get(ctx, q, &res, query, args...)
+		client.Debug(ctx, "\033[1m\033[36mPG %s Load (%.2f ms)\033[1m \033[34m%s\033[0m%s", model, float64(time.Since(start))/1000000, database.OneLine(query), argsToStr(args))
+
+		return wrapError(err)
+	})
+
+	return res, err
+}
+
+// QueryScalar selects a single value from database, such as a count, a sum or an identifier.
+// The query must return exactly one column. If it returns no rows, the error matches database.ErrorNotFound.
+// Unlike QueryOne, the value is scanned directly by pgx, so any type supported by pgx can be used,
+// including time.Time and uuid.UUID.
+// It logs the time taken for the query execution and the query itself in the same format as PostgresClient.Select.
+//
+// Parameters:
+//   - ctx: The context for the operation, used for cancellation and timeout.
+//   - db: The PostgreSQL client or transaction to run the query on.
+//   - model: The name of the model being queried, used for logging.
+//   - query: The SQL query string to be executed in the database.
+//   - args: Optional arguments bound to the $1..$n placeholders of the query.
+//
+// Returns:
+//   - The selected value.
+//   - An error if the operation fails or no rows were found, or nil if it succeeds.
+func QueryScalar[T any](ctx context.Context, db Handle, model string, query string, args ...any) (T, error) {
+	var res T
+	err := db.read(ctx, func(client *PostgresClient, q querier) error {
+		start := time.Now()
+
+		err := q.QueryRow(ctx, query, args...).Scan(&res)
+		client.Debug(ctx, "\033[1m\033[36mPG %s Load (%.2f ms)\033[1m \033[34m%s\033[0m%s", model, float64(time.Since(start))/1000000, database.OneLine(query), argsToStr(args))
+
+		return wrapError(err)
+	})
+
+	return res, err
+}
+
+// QueryIter returns an iterator over the rows selected from database, each scanned into T.
+// Rows are read and scanned one by one, so large result sets can be processed without loading them into memory.
+// The query is executed when the iteration starts. If an error occurs, it is yielded as the last element
+// with the zero value of T. Breaking out of the loop stops the iteration and releases the connection.
+// The time taken, the number of rows and the query are logged when the iteration finishes.
+//
+// Example:
+//
+//	for user, err := range postgres.QueryIter[User](ctx, &postgres.PG, "User", "SELECT * FROM users") {
+//		if err != nil {
+//			return err
+//		}
+//		...
+//	}
+//
+// Parameters:
+//   - ctx: The context for the operation, used for cancellation and timeout.
+//   - db: The PostgreSQL client or transaction to run the query on.
+//   - model: The name of the model being queried, used for logging.
+//   - query: The SQL query string to be executed in the database.
+//   - args: Optional arguments bound to the $1..$n placeholders of the query.
+//
+// Returns:
+//   - An iterator over the selected rows and errors.
+func QueryIter[T any](ctx context.Context, db Handle, model string, query string, args ...any) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		var row, zero T
+		err := db.read(ctx, func(client *PostgresClient, q querier) error {
+			return client.selectEach(ctx, q, model, query, &row, func() error {
+				if !yield(row, nil) {
+					return errStopIteration
+				}
+				row = zero
+				return nil
+			}, args)
+		})
+
+		if err != nil && !errors.Is(err, errStopIteration) {
+			yield(zero, err)
+		}
+	}
+}
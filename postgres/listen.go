@@ -0,0 +1,163 @@
+package postgres
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	listenRetryDelay    = time.Second      // listenRetryDelay: is the delay before the first reconnection attempt of a listener.
+	listenRetryMaxDelay = 30 * time.Second // listenRetryMaxDelay: is the upper bound of the delay between reconnection attempts of a listener.
+	unlistenTimeout     = 5 * time.Second  // unlistenTimeout: is the timeout of UNLISTEN executed before a listener connection is returned to the pool.
+)
+
+// Listen subscribes to PostgreSQL notifications on the given channels.
+// It acquires a dedicated connection from the pool, executes LISTEN for each channel
+// and delivers the received notifications to the returned channel.
+// If the connection is lost, it is reacquired with a growing delay and the channels are listened to again.
+// Notifications sent while the connection is lost are not delivered.
+// The dedicated connection is released and the returned channel is closed when the context is done.
+//
+// Parameters:
+//   - ctx: The context controlling the lifetime of the subscription.
+//   - channels: The names of the channels to listen to.
+//
+// Returns:
+//   - A channel receiving the notifications.
+//   - An error if the initial connection or LISTEN fails.
+func (dst *PostgresClient) Listen(ctx context.Context, channels ...string) (<-chan *pgconn.Notification, error) {
+	conn, err := dst.listen(ctx, channels)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make(chan *pgconn.Notification)
+	go dst.receive(ctx, conn, channels, res)
+
+	return res, nil
+}
+
+// Notify sends a notification with the payload to the channel using pg_notify.
+//
+// Parameters:
+//   - ctx: The context for the operation, used for cancellation and timeout.
+//   - channel: The name of the channel.
+//   - payload: The payload of the notification.
+//
+// Returns:
+//   - An error if the operation fails, or nil if it succeeds.
+func (dst *PostgresClient) Notify(ctx context.Context, channel, payload string) error {
+	_, err := dst.execTag(ctx, dst.client, channel, "Notify", "\033[35m", "SELECT pg_notify($1, $2)", []any{channel, payload})
+	return err
+}
+
+// Notify sends a notification with the payload to the channel inside the transaction.
+// The notification is delivered to listeners only when the transaction is committed.
+//
+// Parameters:
+//   - ctx: The context for the operation, used for cancellation and timeout.
+//   - channel: The name of the channel.
+//   - payload: The payload of the notification.
+//
+// Returns:
+//   - An error if the operation fails, or nil if it succeeds.
+func (dst *PostgresTx) Notify(ctx context.Context, channel, payload string) error {
+	_, err := dst.client.execTag(ctx, dst.tx, channel, "Notify", "\033[35m", "SELECT pg_notify($1, $2)", []any{channel, payload})
+	return err
+}
+
+// listen acquires a connection from the pool and executes LISTEN for each channel on it.
+func (dst *PostgresClient) listen(ctx context.Context, channels []string) (*pgxpool.Conn, error) {
+	conn, err := dst.client.Acquire(ctx)
+	if err != nil {
+		return nil, wrapError(err)
+	}
+
+	for _, channel := range channels {
+		query := "LISTEN " + pgx.Identifier{channel}.Sanitize()
+		if _, err := dst.execTag(ctx, conn, channel, "Listen", "\033[35m", query, nil); err != nil {
+			dst.unlisten(ctx, conn, channels)
+			return nil, err
+		}
+	}
+
+	return conn, nil
+}
+
+// receive waits for notifications on the connection and sends them to the channel until the context is done.
+// The connection is reacquired and the channels are listened to again if it is lost.
+func (dst *PostgresClient) receive(ctx context.Context, conn *pgxpool.Conn, channels []string, res chan<- *pgconn.Notification) {
+	defer close(res)
+
+	for {
+		notification, err := conn.Conn().WaitForNotification(ctx)
+		if err == nil {
+			dst.Debug(ctx, "\033[1m\033[36mPG %s Notification\033[1m \033[35m%q\033[0m", notification.Channel, notification.Payload)
+			select {
+			case res <- notification:
+				continue
+			case <-ctx.Done():
+			}
+		}
+
+		if ctx.Err() != nil {
+			dst.unlisten(ctx, conn, channels)
+			return
+		}
+
+		dst.Warn(ctx, "PostgreSQL listener on %s lost connection: %v", strings.Join(channels, ","), err)
+		destroy(ctx, conn)
+
+		conn = dst.relisten(ctx, channels)
+		if conn == nil {
+			return
+		}
+	}
+}
+
+// relisten reacquires a connection and listens to the channels again, retrying with a growing delay.
+// It returns nil if the context is done before the connection is restored.
+func (dst *PostgresClient) relisten(ctx context.Context, channels []string) *pgxpool.Conn {
+	delay := listenRetryDelay
+	for attempt := 1; ; attempt++ {
+		if err := sleep(ctx, delay); err != nil {
+			return nil
+		}
+
+		conn, err := dst.listen(ctx, channels)
+		if err == nil {
+			dst.Info(ctx, "PostgreSQL listener on %s reconnected after %d attempts", strings.Join(channels, ","), attempt)
+			return conn
+		}
+
+		dst.Warn(ctx, "PostgreSQL listener on %s reconnection attempt %d failed: %v", strings.Join(channels, ","), attempt, err)
+		delay = min(delay*2, listenRetryMaxDelay)
+	}
+}
+
+// unlisten stops listening to all channels on the connection and returns it to the pool, even if ctx is canceled.
+// If UNLISTEN fails or the connection is broken, the connection is closed instead, so that the next user of
+// a pooled connection is not silently subscribed to the channels.
+func (dst *PostgresClient) unlisten(ctx context.Context, conn *pgxpool.Conn, channels []string) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), unlistenTimeout)
+	defer cancel()
+
+	if !conn.Conn().IsClosed() {
+		if _, err := dst.execTag(ctx, conn, strings.Join(channels, ","), "Unlisten", "\033[35m", "UNLISTEN *", nil); err == nil {
+			conn.Release()
+			return
+		}
+	}
+
+	destroy(ctx, conn)
+}
+
+// destroy takes the connection out of the pool and closes it.
+func destroy(ctx context.Context, conn *pgxpool.Conn) {
+	conn.Hijack().Close(context.WithoutCancel(ctx))
+}
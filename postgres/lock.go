@@ -0,0 +1,232 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"time"
+
+	"github.com/ra-company/database"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// TryLock tries to take a session-level advisory lock without waiting.
+// The lock is held on a pooled connection pinned until Unlock is called, so other clients, including
+// other goroutines of this process, cannot take it in the meantime. Locks are not reentrant:
+// trying to take a lock already held by this client returns false.
+//
+// Parameters:
+//   - ctx: The context for the operation, used for cancellation and timeout.
+//   - key: The name of the lock, hashed to the int64 key of the PostgreSQL advisory lock.
+//
+// Returns:
+//   - true if the lock is taken, false if it is held by someone else.
+//   - An error if the operation fails, or nil if it succeeds.
+func (dst *PostgresClient) TryLock(ctx context.Context, key string) (bool, error) {
+	conn, err := dst.client.Acquire(ctx)
+	if err != nil {
+		return false, wrapError(err)
+	}
+
+	ok, err := dst.queryBool(ctx, conn, key, "Lock", "SELECT pg_try_advisory_lock($1)", []any{lockKey(key)})
+	if err != nil || !ok {
+		conn.Release()
+		return false, err
+	}
+
+	dst.pin(ctx, key, conn)
+
+	return true, nil
+}
+
+// Lock takes a session-level advisory lock, waiting until it is released by its current holder
+// or the context is done. The lock is held on a pooled connection pinned until Unlock is called.
+// Locks are not reentrant: taking a lock already held by this client waits until it is unlocked.
+//
+// Parameters:
+//   - ctx: The context for the operation, used for cancellation and timeout.
+//   - key: The name of the lock, hashed to the int64 key of the PostgreSQL advisory lock.
+//
+// Returns:
+//   - An error if the operation fails, or nil if the lock is taken.
+func (dst *PostgresClient) Lock(ctx context.Context, key string) error {
+	conn, err := dst.client.Acquire(ctx)
+	if err != nil {
+		return wrapError(err)
+	}
+
+	if err := dst.lock(ctx, conn, key); err != nil {
+		conn.Release()
+		return err
+	}
+
+	dst.pin(ctx, key, conn)
+
+	return nil
+}
+
+// Unlock releases a session-level advisory lock taken with Lock or TryLock and returns its connection to the pool.
+//
+// Parameters:
+//   - ctx: The context for the operation, used for cancellation and timeout.
+//   - key: The name of the lock.
+//
+// Returns:
+//   - An error matching database.ErrorIncorrectRequest if the lock is not held by this client,
+//     or an error if the operation fails, or nil if it succeeds.
+func (dst *PostgresClient) Unlock(ctx context.Context, key string) error {
+	dst.locksMu.Lock()
+	conn, ok := dst.locks[key]
+	delete(dst.locks, key)
+	dst.locksMu.Unlock()
+
+	if !ok {
+		return fmt.Errorf("%w: advisory lock %q is not held", database.ErrorIncorrectRequest, key)
+	}
+
+	return dst.unlock(ctx, conn, key)
+}
+
+// WithLock takes a session-level advisory lock, calls fn and releases the lock when fn returns.
+// A pooled connection is pinned for the lifetime of the lock. It waits for the lock like Lock.
+//
+// Parameters:
+//   - ctx: The context for the operation, used for cancellation and timeout.
+//   - key: The name of the lock, hashed to the int64 key of the PostgreSQL advisory lock.
+//   - fn: The function called while the lock is held.
+//
+// Returns:
+//   - The error returned by fn, or an error if the lock cannot be taken or released, or nil if it succeeds.
+func (dst *PostgresClient) WithLock(ctx context.Context, key string, fn func(ctx context.Context) error) (err error) {
+	conn, err := dst.client.Acquire(ctx)
+	if err != nil {
+		return wrapError(err)
+	}
+
+	if err := dst.lock(ctx, conn, key); err != nil {
+		conn.Release()
+		return err
+	}
+	defer func() {
+		if unlockErr := dst.unlock(ctx, conn, key); err == nil {
+			err = unlockErr
+		}
+	}()
+
+	return fn(ctx)
+}
+
+// TryWithLock tries to take a session-level advisory lock without waiting and calls fn if it is taken.
+// It is typically used for jobs that must run on a single instance of a service at a time.
+//
+// Parameters:
+//   - ctx: The context for the operation, used for cancellation and timeout.
+//   - key: The name of the lock, hashed to the int64 key of the PostgreSQL advisory lock.
+//   - fn: The function called while the lock is held.
+//
+// Returns:
+//   - true if the lock was taken and fn was called, false if the lock is held by someone else.
+//   - The error returned by fn, or an error if the lock cannot be taken or released, or nil if it succeeds.
+func (dst *PostgresClient) TryWithLock(ctx context.Context, key string, fn func(ctx context.Context) error) (ok bool, err error) {
+	conn, err := dst.client.Acquire(ctx)
+	if err != nil {
+		return false, wrapError(err)
+	}
+
+	ok, err = dst.queryBool(ctx, conn, key, "Lock", "SELECT pg_try_advisory_lock($1)", []any{lockKey(key)})
+	if err != nil || !ok {
+		conn.Release()
+		return false, err
+	}
+	defer func() {
+		if unlockErr := dst.unlock(ctx, conn, key); err == nil {
+			err = unlockErr
+		}
+	}()
+
+	return true, fn(ctx)
+}
+
+// TryLock tries to take a transaction-level advisory lock without waiting.
+// The lock is released automatically when the transaction is committed or rolled back.
+//
+// Parameters:
+//   - ctx: The context for the operation, used for cancellation and timeout.
+//   - key: The name of the lock, hashed to the int64 key of the PostgreSQL advisory lock.
+//
+// Returns:
+//   - true if the lock is taken, false if it is held by someone else.
+//   - An error if the operation fails, or nil if it succeeds.
+func (dst *PostgresTx) TryLock(ctx context.Context, key string) (bool, error) {
+	return dst.client.queryBool(ctx, dst.tx, key, "Lock", "SELECT pg_try_advisory_xact_lock($1)", []any{lockKey(key)})
+}
+
+// Lock takes a transaction-level advisory lock, waiting until it is released by its current holder
+// or the context is done. The lock is released automatically when the transaction is committed or rolled back.
+//
+// Parameters:
+//   - ctx: The context for the operation, used for cancellation and timeout.
+//   - key: The name of the lock, hashed to the int64 key of the PostgreSQL advisory lock.
+//
+// Returns:
+//   - An error if the operation fails, or nil if the lock is taken.
+func (dst *PostgresTx) Lock(ctx context.Context, key string) error {
+	_, err := dst.client.execTag(ctx, dst.tx, key, "Lock", "\033[35m", "SELECT pg_advisory_xact_lock($1)", []any{lockKey(key)})
+	return err
+}
+
+// lock takes a session-level advisory lock on the connection, waiting until it is available.
+func (dst *PostgresClient) lock(ctx context.Context, conn *pgxpool.Conn, key string) error {
+	_, err := dst.execTag(ctx, conn, key, "Lock", "\033[35m", "SELECT pg_advisory_lock($1)", []any{lockKey(key)})
+	return err
+}
+
+// unlock releases a session-level advisory lock on the connection and returns the connection to the pool.
+// If the lock cannot be released, the connection is closed, which releases all its locks on the server.
+// The lock is released even if ctx is canceled, so it is not kept on a pooled connection.
+func (dst *PostgresClient) unlock(ctx context.Context, conn *pgxpool.Conn, key string) error {
+	defer conn.Release()
+
+	ctx = context.WithoutCancel(ctx)
+	if _, err := dst.execTag(ctx, conn, key, "Unlock", "\033[35m", "SELECT pg_advisory_unlock($1)", []any{lockKey(key)}); err != nil {
+		conn.Conn().Close(ctx)
+		return err
+	}
+
+	return nil
+}
+
+// pin stores the connection holding a session-level advisory lock until Unlock is called.
+func (dst *PostgresClient) pin(ctx context.Context, key string, conn *pgxpool.Conn) {
+	dst.locksMu.Lock()
+	defer dst.locksMu.Unlock()
+
+	if dst.locks == nil {
+		dst.locks = map[string]*pgxpool.Conn{}
+	}
+	dst.locks[key] = conn
+}
+
+// queryBool executes a query returning a single boolean value on the given querier.
+// It logs the time taken for the query execution and the query itself, using the provided action name.
+func (dst *PostgresClient) queryBool(ctx context.Context, q querier, model, action, query string, args []any) (bool, error) {
+	start := time.Now()
+
+	var res bool
+	err := q.QueryRow(ctx, query, args...).Scan(&res)
+	dst.Debug(ctx, "\033[1m\033[36mPG %s %s (%.2f ms)\033[1m \033[35m%s\033[0m%s", model, action, float64(time.Since(start))/1000000, database.OneLine(query), argsToStr(args))
+	if err != nil {
+		return false, wrapError(err)
+	}
+
+	return res, nil
+}
+
+// lockKey hashes the name of a lock to the int64 key of a PostgreSQL advisory lock with FNV-1a.
+func lockKey(key string) int64 {
+	h := fnv.New64a()
+	h.Write([]byte(key))
+
+	return int64(h.Sum64())
+}
@@ -0,0 +1,212 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"strings"
+	"time"
+
+	"github.com/ra-company/database"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	DefaultMigrationsTable = "schema_migrations" // DefaultMigrationsTable: is the default name of the table recording the applied migrations.
+)
+
+// Migrator applies and reverts versioned schema migrations read from a file system.
+// It is created by PostgresClient.Migrator. Applied migrations are recorded in the bookkeeping table,
+// and every operation holds a PostgreSQL advisory lock, so only one instance of a service migrates the database at a time.
+// Each migration is applied in its own transaction together with its bookkeeping record.
+type Migrator struct {
+	client     *PostgresClient      // client: is the PostgreSQL client the migrations are applied with.
+	migrations []database.Migration // migrations: are the migrations loaded from the file system, sorted by version.
+	Table      string               // Table: is the name of the bookkeeping table, optionally qualified with a schema name.
+}
+
+// Migrator creates a migrator for the migrations in a directory of the file system.
+// Migration files are named "<version>_<name>.up.sql" and "<version>_<name>.down.sql",
+// such as "0001_create_users.up.sql". The file system is usually an embed.FS:
+//
+//	//go:embed migrations/*.sql
+//	var migrations embed.FS
+//
+//	migrator, err := postgres.PG.Migrator(migrations, "migrations")
+//	...
+//	err = migrator.Up(ctx)
+//
+// Parameters:
+//   - fsys: The file system containing the migration files.
+//   - dir: The directory of the migration files, "." for the root of the file system.
+//
+// Returns:
+//   - A pointer to the new Migrator using the DefaultMigrationsTable bookkeeping table.
+//   - An error if the migrations cannot be loaded.
+func (dst *PostgresClient) Migrator(fsys fs.FS, dir string) (*Migrator, error) {
+	migrations, err := database.LoadMigrations(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Migrator{client: dst, migrations: migrations, Table: DefaultMigrationsTable}, nil
+}
+
+// Up applies all pending migrations in ascending order of versions.
+//
+// Parameters:
+//   - ctx: The context for the operation, used for cancellation and timeout.
+//
+// Returns:
+//   - An error if a migration fails or an applied migration was modified, or nil if it succeeds.
+func (dst *Migrator) Up(ctx context.Context) error {
+	return dst.Goto(ctx, database.MigrationLatest)
+}
+
+// Down reverts the last n applied migrations in descending order of versions.
+//
+// Parameters:
+//   - ctx: The context for the operation, used for cancellation and timeout.
+//   - n: The number of migrations to revert.
+//
+// Returns:
+//   - An error if a migration fails or has no down file, or nil if it succeeds.
+func (dst *Migrator) Down(ctx context.Context, n int) error {
+	return dst.run(ctx, func(conn *pgxpool.Conn, applied []database.AppliedMigration) error {
+		_, down, err := database.PlanMigrations(dst.migrations, applied, database.DownTarget(applied, n))
+		if err != nil {
+			return err
+		}
+
+		return dst.migrate(ctx, conn, nil, down)
+	})
+}
+
+// Goto migrates the database to the given version. Pending migrations up to the version are applied,
+// and applied migrations above the version are reverted.
+//
+// Parameters:
+//   - ctx: The context for the operation, used for cancellation and timeout.
+//   - version: The target version, 0 to revert all migrations.
+//
+// Returns:
+//   - An error if a migration fails, the version is unknown or an applied migration was modified, or nil if it succeeds.
+func (dst *Migrator) Goto(ctx context.Context, version uint64) error {
+	return dst.run(ctx, func(conn *pgxpool.Conn, applied []database.AppliedMigration) error {
+		up, down, err := database.PlanMigrations(dst.migrations, applied, version)
+		if err != nil {
+			return err
+		}
+
+		return dst.migrate(ctx, conn, up, down)
+	})
+}
+
+// Status returns the state of every migration: found in the files, applied to the database, or both.
+//
+// Parameters:
+//   - ctx: The context for the operation, used for cancellation and timeout.
+//
+// Returns:
+//   - The status of every migration, sorted by version.
+//   - An error if the operation fails, or nil if it succeeds.
+func (dst *Migrator) Status(ctx context.Context) ([]database.MigrationStatus, error) {
+	var res []database.MigrationStatus
+	err := dst.run(ctx, func(conn *pgxpool.Conn, applied []database.AppliedMigration) error {
+		res = database.MigrationStatuses(dst.migrations, applied)
+		return nil
+	})
+
+	return res, err
+}
+
+// run acquires a dedicated connection, takes the advisory lock, creates the bookkeeping table if needed
+// and calls fn with the applied migrations. The lock is released when fn returns.
+func (dst *Migrator) run(ctx context.Context, fn func(conn *pgxpool.Conn, applied []database.AppliedMigration) error) error {
+	conn, err := dst.client.client.Acquire(ctx)
+	if err != nil {
+		return wrapError(err)
+	}
+
+	// The lock is taken before the bookkeeping table is created, so instances starting at the same time
+	// do not race on creating it.
+	key := "migrations:" + dst.Table
+	if err := dst.client.lock(ctx, conn, key); err != nil {
+		conn.Release()
+		return err
+	}
+	defer dst.client.unlock(ctx, conn, key)
+
+	table := dst.table()
+	query := `CREATE TABLE IF NOT EXISTS ` + table + ` (
+		version BIGINT PRIMARY KEY,
+		name TEXT NOT NULL,
+		checksum TEXT NOT NULL,
+		applied_at TIMESTAMPTZ NOT NULL DEFAULT now())`
+	if _, err := dst.client.execTag(ctx, conn, "Migration", "Exec", "\033[34m", query, nil); err != nil {
+		return err
+	}
+
+	applied := []database.AppliedMigration{}
+	if err := dst.client.selectRows(ctx, conn, "Migration", `SELECT version, name, checksum, applied_at FROM `+table+` ORDER BY version`, &applied, nil); err != nil {
+		return err
+	}
+
+	return fn(conn, applied)
+}
+
+// migrate reverts and then applies the planned migrations on the connection.
+func (dst *Migrator) migrate(ctx context.Context, conn *pgxpool.Conn, up, down []database.Migration) error {
+	for _, migration := range down {
+		if err := dst.apply(ctx, conn, migration, false); err != nil {
+			return err
+		}
+	}
+	for _, migration := range up {
+		if err := dst.apply(ctx, conn, migration, true); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// apply applies or reverts a migration and updates the bookkeeping table in a single transaction.
+func (dst *Migrator) apply(ctx context.Context, conn *pgxpool.Conn, migration database.Migration, up bool) error {
+	start := time.Now()
+
+	err := pgx.BeginFunc(ctx, conn, func(tx pgx.Tx) error {
+		if !up {
+			if _, err := dst.client.execTag(ctx, tx, "Migration", "Down", "\033[31m", migration.Down, nil); err != nil {
+				return err
+			}
+			_, err := dst.client.execTag(ctx, tx, "Migration", "Delete", "\033[31m", `DELETE FROM `+dst.table()+` WHERE version = $1`, []any{migration.Version})
+			return err
+		}
+
+		if _, err := dst.client.execTag(ctx, tx, "Migration", "Up", "\033[32m", migration.Up, nil); err != nil {
+			return err
+		}
+		_, err := dst.client.execTag(ctx, tx, "Migration", "Create", "\033[32m", `INSERT INTO `+dst.table()+` (version, name, checksum) VALUES ($1, $2, $3)`, []any{migration.Version, migration.Name, migration.Checksum})
+		return err
+	})
+	if err != nil {
+		dst.client.Error(ctx, err)
+		return fmt.Errorf("migration %d_%s: %w", migration.Version, migration.Name, wrapError(err))
+	}
+
+	action := "applied"
+	if !up {
+		action = "reverted"
+	}
+	dst.client.Info(ctx, "PostgreSQL migration %d_%s %s (%.2f ms)", migration.Version, migration.Name, action, float64(time.Since(start))/1000000)
+
+	return nil
+}
+
+// table returns the sanitized name of the bookkeeping table.
+func (dst *Migrator) table() string {
+	return pgx.Identifier(strings.Split(dst.Table, ".")).Sanitize()
+}
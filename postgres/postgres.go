@@ -3,9 +3,8 @@ package postgres
 import (
 	"context"
 	"fmt"
-	"log"
-	"net/url"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/georgysavva/scany/v2/pgxscan"
@@ -23,8 +22,15 @@ var (
 )
 
 type PostgresClient struct {
-	logging.CustomLogger               // CustomLogger: is an embedded field that allows the PostgresClient to use custom logging functionality.
-	client               *pgxpool.Pool // client: is a pointer to the PostgreSQL connection pool.
+	logging.CustomLogger                          // CustomLogger: is an embedded field that allows the PostgresClient to use custom logging functionality.
+	client               *pgxpool.Pool            // client: is a pointer to the PostgreSQL connection pool.
+	replica              *pgxpool.Pool            // replica: is a pointer to the connection pool of the read replicas, nil if no replica is connected.
+	statements           map[string]string        // statements: are the SQL queries of the statements registered with Prepare, by name.
+	statementsMu         sync.RWMutex             // statementsMu: protects statements.
+	locks                map[string]*pgxpool.Conn // locks: are the connections pinned by the session-level advisory locks taken with Lock and TryLock, by key.
+	locksMu              sync.Mutex               // locksMu: protects locks.
+	RetryPolicy          RetryPolicy              // RetryPolicy: defines how transactions failing with serialization failures or deadlocks are retried.
+	ConnectRetry         database.ConnectRetry    // ConnectRetry: defines how a failed connection to the server is retried by Connect and Start.
 }
 
 // Start initializes the PostgreSQL connection pool with the provided credentials and database information.
@@ -34,6 +40,8 @@ type PostgresClient struct {
 // This allows the client to connect to a primary server for read-write operations.
 // It also pings the database to ensure the connection is established.
 // If the connection is successful, it logs the connection details.
+// Use StartWithConfig to set pool options, timeouts and other connection parameters,
+// or Connect to handle connection errors without exiting the application.
 //
 // Parameters:
 //   - ctx: The context for the operation, used for cancellation and timeout.
@@ -42,37 +50,120 @@ type PostgresClient struct {
 //   - password: The password for the PostgreSQL database.
 //   - db: The name of the PostgreSQL database to connect to.
 func (dst *PostgresClient) Start(ctx context.Context, host, username, password string, db string) {
-	var err error
-	var connectionString string
+	dst.StartWithConfig(ctx, Config{
+		Host:     host,
+		Username: username,
+		Password: password,
+		Database: db,
+	})
+}
 
-	if strings.Contains(host, ",") {
-		connectionString = fmt.Sprintf("postgres://%s@%s/%s?target_session_attrs=read-write", url.UserPassword(username, password), host, db)
-	} else {
-		connectionString = fmt.Sprintf("postgres://%s@%s/%s", url.UserPassword(username, password), host, db)
+// StartWithConfig initializes the PostgreSQL connection pool with the provided configuration.
+// It works the same way as Start, but also applies the pool options, timeouts and connection parameters from the configuration.
+// It logs an error and exits the application if the connection fails.
+//
+// Parameters:
+//   - ctx: The context for the operation, used for cancellation and timeout.
+//   - cfg: The connection and pool configuration.
+func (dst *PostgresClient) StartWithConfig(ctx context.Context, cfg Config) {
+	if err := dst.ConnectWithConfig(ctx, cfg); err != nil {
+		dst.Fatal(ctx, "PostgreSQL connection error: %v", err)
 	}
+}
 
-	dst.client, err = pgxpool.New(ctx, connectionString)
+// Connect initializes the PostgreSQL connection pool with the provided credentials and database information.
+// It works the same way as Start, but returns an error instead of exiting the application if the connection fails,
+// so the caller decides whether a failed connection is fatal.
+// The connection is retried according to the ConnectRetry settings of the client.
+//
+// Parameters:
+//   - ctx: The context for the operation, used for cancellation and timeout.
+//   - host: The host (with port) where the PostgreSQL database is running.
+//   - username: The username for the PostgreSQL database.
+//   - password: The password for the PostgreSQL database.
+//   - db: The name of the PostgreSQL database to connect to.
+//
+// Returns:
+//   - An error if the connection fails, or nil if it succeeds.
+func (dst *PostgresClient) Connect(ctx context.Context, host, username, password string, db string) error {
+	return dst.ConnectWithConfig(ctx, Config{
+		Host:     host,
+		Username: username,
+		Password: password,
+		Database: db,
+	})
+}
+
+// ConnectWithConfig initializes the PostgreSQL connection pool with the provided configuration.
+// It works the same way as StartWithConfig, but returns an error instead of exiting the application if the connection fails.
+// The connection is retried according to the ConnectRetry settings of the client.
+//
+// Parameters:
+//   - ctx: The context for the operation, used for cancellation and timeout.
+//   - cfg: The connection and pool configuration.
+//
+// Returns:
+//   - An error if the connection fails, or nil if it succeeds.
+func (dst *PostgresClient) ConnectWithConfig(ctx context.Context, cfg Config) error {
+	poolConfig, err := cfg.poolConfig()
 	if err != nil {
-		dst.Fatal(ctx, "PostgreSQL connection error: %v", err)
+		return err
 	}
 
-	err = dst.client.Ping(ctx)
+	var fullVersion string
+	err = dst.ConnectRetry.Do(ctx, func() error {
+		var err error
+		fullVersion, err = dst.connect(ctx, poolConfig)
+		return err
+	}, func(err error, attempt int, delay time.Duration) {
+		dst.Warn(ctx, "PostgreSQL connection attempt %d of %d failed, retry in %.2f ms: %v", attempt, dst.ConnectRetry.Attempts, float64(delay)/1000000, err)
+	})
 	if err != nil {
-		dst.Fatal(ctx, "PostgreSQL connection error: %v", err)
+		return err
 	}
 
-	if strings.Contains(host, ",") {
-		dst.Info(ctx, "Connected to PostgreSQL Database: cluster - %v, database - %v, user - %v", host, db, username)
+	if strings.Contains(cfg.Host, ",") {
+		dst.Info(ctx, "Connected to PostgreSQL Database: cluster - %v, database - %v, user - %v", cfg.Host, cfg.Database, cfg.Username)
 	} else {
-		dst.Info(ctx, "Connected to PostgreSQL Database: host - %v, database - %v, user - %v", host, db, username)
+		dst.Info(ctx, "Connected to PostgreSQL Database: host - %v, database - %v, user - %v", cfg.Host, cfg.Database, cfg.Username)
+	}
+	dst.Info(ctx, "PostgreSQL version %s", fullVersion)
+
+	return dst.connectReplica(ctx, cfg)
+}
+
+// connect creates the connection pool of the primary server and queries the server version.
+func (dst *PostgresClient) connect(ctx context.Context, poolConfig *pgxpool.Config) (string, error) {
+	client, fullVersion, err := dst.newPool(ctx, poolConfig)
+	if err != nil {
+		return "", err
+	}
+
+	dst.client = client
+
+	return fullVersion, nil
+}
+
+// newPool creates a connection pool, pings the database and queries the server version.
+// The statements registered with Prepare are prepared on every new connection of the pool.
+// The pool is closed if any of the steps fails.
+func (dst *PostgresClient) newPool(ctx context.Context, poolConfig *pgxpool.Config) (*pgxpool.Pool, string, error) {
+	poolConfig = poolConfig.Copy()
+	poolConfig.AfterConnect = dst.prepareStatements
+
+	client, err := pgxpool.NewWithConfig(ctx, poolConfig)
+	if err != nil {
+		return nil, "", err
 	}
 
 	var fullVersion string
-	err = dst.client.QueryRow(ctx, "SELECT version()").Scan(&fullVersion)
+	err = client.QueryRow(ctx, "SELECT version()").Scan(&fullVersion)
 	if err != nil {
-		log.Fatalf("Query failed: %v", err)
+		client.Close()
+		return nil, "", err
 	}
-	dst.Info(ctx, "PostgreSQL version %s", fullVersion)
+
+	return client, fullVersion, nil
 }
 
 // Stop closes the PostgreSQL connection pool and logs a message indicating that the disconnection was successful.
@@ -84,6 +175,10 @@ func (dst *PostgresClient) Start(ctx context.Context, host, username, password s
 // Parameters:
 //   - ctx: The context for the operation, used for cancellation and timeout.
 func (dst *PostgresClient) Stop(ctx context.Context) {
+	if dst.replica != nil {
+		dst.replica.Close()
+		dst.replica = nil
+	}
 	dst.client.Close()
 	dst.Info(ctx, "Disconnected from PostgreSQL Database")
 }
@@ -93,19 +188,40 @@ func (dst *PostgresClient) Stop(ctx context.Context) {
 // It logs the time taken for the query execution and the query itself for debugging purposes.
 // If the query execution is successful, it returns nil.
 // If an error occurs during the query execution, it returns the error.
+// The query is executed on a read replica if one is connected, unless the context is created with WithPrimary.
 //
 // Parameters:
 //   - ctx: The context for the operation, used for cancellation and timeout.
 //   - model: The name of the model being queried, used for logging.
 //   - query: The SQL query string to be executed in the database.
 //   - data: A pointer to the data structure where the result will be scanned into.
-func (dst *PostgresClient) Select(ctx context.Context, model string, query string, data any) error {
-	start := time.Now()
-
-	err := pgxscan.Select(ctx, dst.client, data, query)
-	dst.Debug(ctx, "\033[1m\033[36mPG %s Load (%.2f ms)\033[1m \033[34m%s\033[0m", model, float64(time.Since(start))/1000000, database.OneLine(query))
+//   - args: Optional arguments bound to the $1..$n placeholders of the query.
+func (dst *PostgresClient) Select(ctx context.Context, model string, query string, data any, args ...any) error {
+	return dst.read(ctx, func(client *PostgresClient, q querier) error {
+		return client.selectRows(ctx, q, model, query, data, args)
+	})
+}
 
-	return err
+// SelectEach selects data from database and scans it row by row into the provided data structure.
+// Unlike Select, it does not load the whole result into memory: each row is scanned into data,
+// then fn is called to process it before the next row is read. Returning an error from fn stops the iteration.
+// It logs the time taken for the whole iteration, the number of processed rows and the query itself when the iteration finishes.
+// Like Select, the query is executed on a read replica if one is connected.
+//
+// Parameters:
+//   - ctx: The context for the operation, used for cancellation and timeout.
+//   - model: The name of the model being queried, used for logging.
+//   - query: The SQL query string to be executed in the database.
+//   - data: A pointer to the data structure each row will be scanned into.
+//   - fn: The function called after each row is scanned.
+//   - args: Optional arguments bound to the $1..$n placeholders of the query.
+//
+// Returns:
+//   - An error returned by fn, or an error if the operation fails, or nil if it succeeds.
+func (dst *PostgresClient) SelectEach(ctx context.Context, model string, query string, data any, fn func() error, args ...any) error {
+	return dst.read(ctx, func(client *PostgresClient, q querier) error {
+		return client.selectEach(ctx, q, model, query, data, fn, args)
+	})
 }
 
 // Insert data into database and return inserted IDs
@@ -118,53 +234,20 @@ func (dst *PostgresClient) Select(ctx context.Context, model string, query strin
 //   - ctx: The context for the operation, used for cancellation and timeout.
 //   - model: The name of the model being inserted, used for logging.
 //   - query: The SQL query string for inserting data into the database.
+//   - args: Optional arguments bound to the $1..$n placeholders of the query.
 //
 // Returns:
 //   - A slice of uint containing the IDs of the inserted records.
 //   - An error if the operation fails, or nil if it succeeds.
-func (dst *PostgresClient) Insert(ctx context.Context, model string, query string) ([]uint, error) {
-	start := time.Now()
-
+func (dst *PostgresClient) Insert(ctx context.Context, model string, query string, args ...any) ([]uint, error) {
 	ids := []uint{}
-	tx, err := dst.client.Begin(ctx)
-	dst.Debug(ctx, "\033[1m\033[36mPG TRANSACTION (%.2f ms)\033[0m \033[1m\033[35mBEGIN\033[0m", float64(time.Since(start))/1000000)
-	if err != nil {
-		return ids, err
-	}
-
-	start = time.Now()
-
-	var res pgx.Rows
-	res, err = tx.Query(ctx, query+" RETURNING id")
-	dst.Debug(ctx, "\033[1m\033[36mPG %s Create (%.2f ms)\033[1m \033[32m%s\033[0m", model, float64(time.Since(start))/1000000, database.OneLine(query))
-	if err != nil {
-		tx.Rollback(ctx)
-		dst.Error(ctx, err)
-		dst.Debug(ctx, "\033[1m\033[36mPG TRANSACTION (%.2f ms)\033[0m \033[1m\033[31mROLLBACK\033[0m", float64(time.Since(start))/1000000)
-		return ids, err
-	}
-
-	var n uint
-	_, err = pgx.ForEachRow(res, []any{&n}, func() error {
-		ids = append(ids, n)
-		return nil
+	err := dst.WithTx(ctx, pgx.TxOptions{}, func(tx *PostgresTx) error {
+		var err error
+		ids, err = tx.Insert(ctx, model, query, args...)
+		return err
 	})
 
-	if err != nil {
-		tx.Rollback(ctx)
-		dst.Debug(ctx, "\033[1m\033[36mPG TRANSACTION (%.2f ms)\033[0m \033[1m\033[31mROLLBACK\033[0m", float64(time.Since(start))/1000000)
-		return ids, err
-	}
-
-	start = time.Now()
-
-	err = tx.Commit(ctx)
-	dst.Debug(ctx, "\033[1m\033[36mPG TRANSACTION (%.2f ms)\033[0m \033[1m\033[35mCOMMIT\033[0m", float64(time.Since(start))/1000000)
-	if err != nil {
-		return ids, err
-	}
-
-	return ids, nil
+	return ids, err
 }
 
 // InsertUUID data into database and return inserted UUIDs
@@ -177,53 +260,20 @@ func (dst *PostgresClient) Insert(ctx context.Context, model string, query strin
 //   - ctx: The context for the operation, used for cancellation and timeout.
 //   - model: The name of the model being inserted, used for logging.
 //   - query: The SQL query string for inserting data into the database.
+//   - args: Optional arguments bound to the $1..$n placeholders of the query.
 //
 // Returns:
 //   - A slice of uuid.UUID containing the UUIDs of the inserted records.
 //   - An error if the operation fails, or nil if it succeeds.
-func (dst *PostgresClient) InsertUUID(ctx context.Context, model string, query string) ([]uuid.UUID, error) {
-	start := time.Now()
-
+func (dst *PostgresClient) InsertUUID(ctx context.Context, model string, query string, args ...any) ([]uuid.UUID, error) {
 	ids := []uuid.UUID{}
-	tx, err := dst.client.Begin(ctx)
-	dst.Debug(ctx, "\033[1m\033[36mPG TRANSACTION (%.2f ms)\033[0m \033[1m\033[35mBEGIN\033[0m", float64(time.Since(start))/1000000)
-	if err != nil {
-		return ids, err
-	}
-
-	start = time.Now()
-
-	var res pgx.Rows
-	res, err = tx.Query(ctx, query+" RETURNING id")
-	dst.Debug(ctx, "\033[1m\033[36mPG %s Create (%.2f ms)\033[1m \033[32m%s\033[0m", model, float64(time.Since(start))/1000000, database.OneLine(query))
-	if err != nil {
-		tx.Rollback(ctx)
-		dst.Error(ctx, err)
-		dst.Debug(ctx, "\033[1m\033[36mPG TRANSACTION (%.2f ms)\033[0m \033[1m\033[31mROLLBACK\033[0m", float64(time.Since(start))/1000000)
-		return ids, err
-	}
-
-	var n uuid.UUID
-	_, err = pgx.ForEachRow(res, []any{&n}, func() error {
-		ids = append(ids, n)
-		return nil
+	err := dst.WithTx(ctx, pgx.TxOptions{}, func(tx *PostgresTx) error {
+		var err error
+		ids, err = tx.InsertUUID(ctx, model, query, args...)
+		return err
 	})
 
-	if err != nil {
-		tx.Rollback(ctx)
-		dst.Debug(ctx, "\033[1m\033[36mPG TRANSACTION (%.2f ms)\033[0m \033[1m\033[31mROLLBACK\033[0m", float64(time.Since(start))/1000000)
-		return ids, err
-	}
-
-	start = time.Now()
-
-	err = tx.Commit(ctx)
-	dst.Debug(ctx, "\033[1m\033[36mPG TRANSACTION (%.2f ms)\033[0m \033[1m\033[35mCOMMIT\033[0m", float64(time.Since(start))/1000000)
-	if err != nil {
-		return ids, err
-	}
-
-	return ids, nil
+	return ids, err
 }
 
 // Update data in database and return affected rows count
@@ -236,51 +286,27 @@ func (dst *PostgresClient) InsertUUID(ctx context.Context, model string, query s
 //   - ctx: The context for the operation, used for cancellation and timeout.
 //   - model: The name of the model being updated, used for logging.
 //   - query: The SQL query string for updating data in the database.
+//   - args: Optional arguments bound to the $1..$n placeholders of the query.
 //
 // Returns:
 //   - A uint representing the number of affected rows.
 //   - An error if the operation fails, or nil if it succeeds.
-func (dst *PostgresClient) Update(ctx context.Context, model string, query string) (uint, error) {
+func (dst *PostgresClient) Update(ctx context.Context, model string, query string, args ...any) (uint, error) {
 	if query == "" {
 		return 0, nil
 	}
 
-	start := time.Now()
-
-	tx, err := dst.client.Begin(ctx)
-	dst.Debug(ctx, "\033[1m\033[36mPG TRANSACTION (%.2f ms)\033[0m \033[1m\033[35mBEGIN\033[0m", float64(time.Since(start))/1000000)
-	if err != nil {
-		return 0, err
-	}
-
-	start = time.Now()
-
-	var res pgconn.CommandTag
-	res, err = tx.Exec(ctx, query)
-	dst.Debug(ctx, "\033[1m\033[36mPG %s Update (%.2f ms)\033[1m \033[33m%s\033[0m", model, float64(time.Since(start))/1000000, database.OneLine(query))
-	if err != nil {
-		tx.Rollback(ctx)
-		dst.Error(ctx, err)
-		dst.Debug(ctx, "\033[1m\033[36mPG TRANSACTION (%.2f ms)\033[0m \033[1m\033[31mROLLBACK\033[0m", float64(time.Since(start))/1000000)
-		return 0, err
-	}
-
-	if !res.Update() {
-		tx.Rollback(ctx)
-		dst.Error(ctx, database.ErrorIncorrectRequest)
-		dst.Debug(ctx, "\033[1m\033[36mPG TRANSACTION (%.2f ms)\033[0m \033[1m\033[31mROLLBACK\033[0m", float64(time.Since(start))/1000000)
-		return 0, database.ErrorIncorrectRequest
-	}
-
-	start = time.Now()
-
-	err = tx.Commit(ctx)
-	dst.Debug(ctx, "\033[1m\033[36mPG TRANSACTION (%.2f ms)\033[0m \033[1m\033[35mCOMMIT\033[0m", float64(time.Since(start))/1000000)
+	var n uint
+	err := dst.WithTx(ctx, pgx.TxOptions{}, func(tx *PostgresTx) error {
+		var err error
+		n, err = tx.Update(ctx, model, query, args...)
+		return err
+	})
 	if err != nil {
 		return 0, err
 	}
 
-	return uint(res.RowsAffected()), nil
+	return n, nil
 }
 
 // Delete data from database
@@ -293,101 +319,77 @@ func (dst *PostgresClient) Update(ctx context.Context, model string, query strin
 //   - ctx: The context for the operation, used for cancellation and timeout.
 //   - model: The name of the model being deleted, used for logging.
 //   - query: The SQL query string for deleting data from the database.
+//   - args: Optional arguments bound to the $1..$n placeholders of the query.
 //
 // Returns:
 //   - A uint representing the number of affected rows.
 //   - An error if the operation fails, or nil if it succeeds.
-func (dst *PostgresClient) Delete(ctx context.Context, model string, query string) (uint, error) {
-	start := time.Now()
-
-	tx, err := dst.client.Begin(ctx)
-	dst.Debug(ctx, "\033[1m\033[36mPG TRANSACTION (%.2f ms)\033[0m \033[1m\033[35mBEGIN\033[0m", float64(time.Since(start))/1000000)
-	if err != nil {
-		return 0, err
-	}
-
-	start = time.Now()
-
-	var res pgconn.CommandTag
-	res, err = tx.Exec(ctx, query)
-	dst.Debug(ctx, "\033[1m\033[36mPG %s Delete (%.2f ms)\033[1m \033[31m%s\033[0m", model, float64(time.Since(start))/1000000, database.OneLine(query))
-	if err != nil {
-		tx.Rollback(ctx)
-		dst.Error(ctx, err)
-		dst.Debug(ctx, "\033[1m\033[36mPG TRANSACTION (%.2f ms)\033[0m \033[1m\033[31mROLLBACK\033[0m", float64(time.Since(start))/1000000)
-		return 0, err
-	}
-
-	if !res.Delete() {
-		tx.Rollback(ctx)
-		dst.Error(ctx, database.ErrorIncorrectRequest)
-		dst.Debug(ctx, "\033[1m\033[36mPG TRANSACTION (%.2f ms)\033[0m \033[1m\033[31mROLLBACK\033[0m", float64(time.Since(start))/1000000)
-		return 0, database.ErrorIncorrectRequest
-	}
-
-	start = time.Now()
-
-	err = tx.Commit(ctx)
-	dst.Debug(ctx, "\033[1m\033[36mPG TRANSACTION (%.2f ms)\033[0m \033[1m\033[35mCOMMIT\033[0m", float64(time.Since(start))/1000000)
+func (dst *PostgresClient) Delete(ctx context.Context, model string, query string, args ...any) (uint, error) {
+	var n uint
+	err := dst.WithTx(ctx, pgx.TxOptions{}, func(tx *PostgresTx) error {
+		var err error
+		n, err = tx.Delete(ctx, model, query, args...)
+		return err
+	})
 	if err != nil {
 		return 0, err
 	}
 
-	return uint(res.RowsAffected()), nil
+	return n, nil
 }
 
 // Return records count in database
 // The function executes a SQL query to count the number of records in a specified model.
 // It logs the time taken for the query execution and the query itself for debugging purposes.
 // If the query execution is successful, it returns the count of records as a uint64.
+// The query is executed on a read replica if one is connected, unless the context is created with WithPrimary.
 // If an error occurs during the query execution, it returns an error.
 //
 // Parameters:
 //   - ctx: The context for the operation, used for cancellation and timeout.
 //   - model: The name of the model being counted, used for logging.
 //   - query: The SQL query string for counting records in the database.
+//   - args: Optional arguments bound to the $1..$n placeholders of the query.
 //
 // Returns:
 //   - A uint64 representing the number of records in the specified model.
 //   - An error if the operation fails, or nil if it succeeds.
-func (dst *PostgresClient) Count(ctx context.Context, model string, query string) (uint64, error) {
-	start := time.Now()
-
+func (dst *PostgresClient) Count(ctx context.Context, model string, query string, args ...any) (uint64, error) {
 	var n uint64
-	err := dst.client.QueryRow(ctx, query).Scan(&n)
-	dst.Debug(ctx, "\033[1m\033[36mPG %s Count (%.2f ms)\033[1m \033[34m%s\033[0m", model, float64(time.Since(start))/1000000, database.OneLine(query))
-	if err != nil {
-		return 0, err
-	}
+	err := dst.read(ctx, func(client *PostgresClient, q querier) error {
+		var err error
+		n, err = client.queryUint64(ctx, q, model, "Count", query, args)
+		return err
+	})
 
-	return n, nil
+	return n, err
 }
 
 // Return maximum field value in database
 // The function executes a SQL query to find the maximum value of a specified field in a model.
 // It logs the time taken for the query execution and the query itself for debugging purposes.
 // If the query execution is successful, it returns the maximum value as a uint64.
+// The query is executed on a read replica if one is connected, unless the context is created with WithPrimary.
 // If an error occurs during the query execution, it returns an error.
 //
 // Parameters:
 //   - ctx: The context for the operation, used for cancellation and timeout.
 //   - model: The name of the model being queried, used for logging.
 //   - query: The SQL query string for finding the maximum value in the database.
+//   - args: Optional arguments bound to the $1..$n placeholders of the query.
 //
 // Returns:
 //   - A uint64 representing the maximum value of the specified field in the model.
 //   - An error if the operation fails, or nil if it succeeds.
-func (dst *PostgresClient) Max(ctx context.Context, model, query string) (uint64, error) {
-	start := time.Now()
-
+func (dst *PostgresClient) Max(ctx context.Context, model, query string, args ...any) (uint64, error) {
 	var n uint64
-	err := dst.client.QueryRow(ctx, query).Scan(&n)
-	dst.Debug(ctx, "\033[1m\033[36mPG %s MAX (%.2f ms)\033[1m \033[34m%s\033[0m", model, float64(time.Since(start))/1000000, database.OneLine(query))
-	if err != nil {
-		return 0, err
-	}
+	err := dst.read(ctx, func(client *PostgresClient, q querier) error {
+		var err error
+		n, err = client.queryUint64(ctx, q, model, "MAX", query, args)
+		return err
+	})
 
-	return n, nil
+	return n, err
 }
 
 // Execute query without result
@@ -399,14 +401,12 @@ func (dst *PostgresClient) Max(ctx context.Context, model, query string) (uint64
 //   - ctx: The context for the operation, used for cancellation and timeout.
 //   - model: The name of the model being queried, used for logging.
 //   - query: The SQL query string to be executed in the database.
+//   - args: Optional arguments bound to the $1..$n placeholders of the query.
 //
 // Returns:
 //   - An error if the operation fails, or nil if it succeeds.
-func (dst *PostgresClient) Exec(ctx context.Context, model string, query string) error {
-	start := time.Now()
-
-	_, err := dst.client.Exec(ctx, query)
-	dst.Debug(ctx, "\033[1m\033[36mPG %s Exec (%.2f ms)\033[1m \033[34m%s\033[0m", model, float64(time.Since(start))/1000000, database.OneLine(query))
+func (dst *PostgresClient) Exec(ctx context.Context, model string, query string, args ...any) error {
+	_, err := dst.execTag(ctx, dst.client, model, "Exec", "\033[34m", query, args)
 	if err != nil {
 		return err
 	}
@@ -414,6 +414,107 @@ func (dst *PostgresClient) Exec(ctx context.Context, model string, query string)
 	return nil
 }
 
+// querier is the part of the pgx API shared by *pgxpool.Pool and pgx.Tx.
+// It allows the same statement helpers to be used both on the pool and inside a transaction.
+type querier interface {
+	Exec(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+	CopyFrom(ctx context.Context, tableName pgx.Identifier, columnNames []string, rowSrc pgx.CopyFromSource) (int64, error)
+	SendBatch(ctx context.Context, b *pgx.Batch) pgx.BatchResults
+}
+
+// selectRows executes a select query on the given querier and scans the result into data.
+// It logs the time taken for the query execution and the query itself.
+func (dst *PostgresClient) selectRows(ctx context.Context, q querier, model string, query string, data any, args []any) error {
+	start := time.Now()
+
+	err := pgxscan.Select(ctx, q, data, query, args...)
+	dst.Debug(ctx, "\033[1m\033[36mPG %s Load (%.2f ms)\033[1m \033[34m%s\033[0m%s", model, float64(time.Since(start))/1000000, database.OneLine(query), argsToStr(args))
+
+	return wrapError(err)
+}
+
+// selectEach executes a select query on the given querier and scans the result row by row into data,
+// calling fn after each row. It logs the time taken for the whole iteration, the number of rows and the query itself.
+func (dst *PostgresClient) selectEach(ctx context.Context, q querier, model string, query string, data any, fn func() error, args []any) error {
+	start := time.Now()
+
+	n := 0
+	defer func() {
+		dst.Debug(ctx, "\033[1m\033[36mPG %s Load (%.2f ms, %d rows)\033[1m \033[34m%s\033[0m%s", model, float64(time.Since(start))/1000000, n, database.OneLine(query), argsToStr(args))
+	}()
+
+	rows, err := q.Query(ctx, query, args...)
+	if err != nil {
+		return wrapError(err)
+	}
+	defer rows.Close()
+
+	scanner := pgxscan.NewRowScanner(rows)
+	for rows.Next() {
+		if err := scanner.Scan(data); err != nil {
+			return wrapError(err)
+		}
+		n++
+
+		if err := fn(); err != nil {
+			return err
+		}
+	}
+
+	return wrapError(rows.Err())
+}
+
+// insertReturning executes an insert query with "RETURNING id" appended on the given querier
+// and collects the returned identifiers of type T.
+// It logs the time taken for the query execution and the query itself.
+func insertReturning[T any](ctx context.Context, dst *PostgresClient, q querier, model string, query string, args []any) ([]T, error) {
+	start := time.Now()
+
+	ids := []T{}
+	res, err := q.Query(ctx, query+" RETURNING id", args...)
+	dst.Debug(ctx, "\033[1m\033[36mPG %s Create (%.2f ms)\033[1m \033[32m%s\033[0m%s", model, float64(time.Since(start))/1000000, database.OneLine(query), argsToStr(args))
+	if err != nil {
+		dst.Error(ctx, err)
+		return ids, wrapError(err)
+	}
+
+	var n T
+	_, err = pgx.ForEachRow(res, []any{&n}, func() error {
+		ids = append(ids, n)
+		return nil
+	})
+
+	return ids, wrapError(err)
+}
+
+// execTag executes a query on the given querier and returns its command tag.
+// It logs the time taken for the query execution and the query itself, using the provided action name and color.
+func (dst *PostgresClient) execTag(ctx context.Context, q querier, model, action, color, query string, args []any) (pgconn.CommandTag, error) {
+	start := time.Now()
+
+	res, err := q.Exec(ctx, query, args...)
+	dst.Debug(ctx, "\033[1m\033[36mPG %s %s (%.2f ms)\033[1m %s%s\033[0m%s", model, action, float64(time.Since(start))/1000000, color, database.OneLine(query), argsToStr(args))
+
+	return res, wrapError(err)
+}
+
+// queryUint64 executes a query returning a single unsigned integer value on the given querier.
+// It logs the time taken for the query execution and the query itself, using the provided action name.
+func (dst *PostgresClient) queryUint64(ctx context.Context, q querier, model, action, query string, args []any) (uint64, error) {
+	start := time.Now()
+
+	var n uint64
+	err := q.QueryRow(ctx, query, args...).Scan(&n)
+	dst.Debug(ctx, "\033[1m\033[36mPG %s %s (%.2f ms)\033[1m \033[34m%s\033[0m%s", model, action, float64(time.Since(start))/1000000, database.OneLine(query), argsToStr(args))
+	if err != nil {
+		return 0, wrapError(err)
+	}
+
+	return n, nil
+}
+
 // Put query string to the log
 // The function logs the SQL query string along with the time taken for the query execution.
 // It is typically used for debugging purposes to track the performance of SQL queries.
@@ -426,6 +527,38 @@ func (dst *PostgresClient) LogSelect(ctx context.Context, model string, query st
 	dst.Debug(ctx, "\033[1m\033[36mPG %s Load (%.2f ms)\033[1m \033[34m%s\033[0m", model, float64(time.Since(start))/1000000, database.OneLine(query))
 }
 
+// argsToStr renders query arguments for the debug log.
+// The arguments are printed separately from the query, each prefixed with its placeholder number,
+// so that the logged query stays identical to the one sent to the database.
+// It returns an empty string if there are no arguments.
+//
+// Parameters:
+//   - args: The arguments bound to the query placeholders.
+//
+// Returns:
+//   - A string like ` [$1=42, $2="name"]` or an empty string.
+func argsToStr(args []any) string {
+	if len(args) == 0 {
+		return ""
+	}
+
+	parts := make([]string, len(args))
+	for i, arg := range args {
+		switch v := arg.(type) {
+		case string:
+			parts[i] = fmt.Sprintf("$%d=%q", i+1, v)
+		case []byte:
+			parts[i] = fmt.Sprintf("$%d=<%d bytes>", i+1, len(v))
+		case nil:
+			parts[i] = fmt.Sprintf("$%d=NULL", i+1)
+		default:
+			parts[i] = fmt.Sprintf("$%d=%v", i+1, v)
+		}
+	}
+
+	return " [" + strings.Join(parts, ", ") + "]"
+}
+
 // Client returns the PostgreSQL connection pool.
 // It is used to access the underlying pgxpool.Pool instance for executing queries and transactions.
 // This function is typically called when you need to perform operations directly on the PostgreSQL database.
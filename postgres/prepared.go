@@ -0,0 +1,209 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"time"
+
+	"github.com/georgysavva/scany/v2/pgxscan"
+	"github.com/ra-company/database"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Prepare registers a named statement that is prepared once per connection and executed by name
+// with SelectPrepared and ExecPrepared, so PostgreSQL does not parse and plan the query on every call.
+// Registered statements are prepared on every new connection of the pool and of the read replicas.
+// If the client is already connected, the statement is prepared on a connection right away to check it.
+// Registering the same name again with the same query does nothing.
+//
+// Parameters:
+//   - ctx: The context for the operation, used for cancellation and timeout.
+//   - name: The name of the statement.
+//   - query: The SQL query string of the statement, with $1..$n placeholders.
+//
+// Returns:
+//   - An error matching database.ErrorIncorrectParameters if the name is already used by another query,
+//     or an error if the statement cannot be prepared, or nil if it succeeds.
+func (dst *PostgresClient) Prepare(ctx context.Context, name string, query string) error {
+	dst.statementsMu.Lock()
+	if registered, ok := dst.statements[name]; ok {
+		dst.statementsMu.Unlock()
+		if registered != query {
+			return fmt.Errorf("%w: prepared statement %q is already registered with another query", database.ErrorIncorrectParameters, name)
+		}
+		return nil
+	}
+	if dst.statements == nil {
+		dst.statements = map[string]string{}
+	}
+	dst.statements[name] = query
+	dst.statementsMu.Unlock()
+
+	if dst.client == nil {
+		return nil
+	}
+
+	start := time.Now()
+
+	err := dst.client.AcquireFunc(ctx, func(conn *pgxpool.Conn) error {
+		_, err := conn.Conn().Prepare(ctx, name, query)
+		return err
+	})
+	dst.Debug(ctx, "\033[1m\033[36mPG PREPARE %s (%.2f ms)\033[1m \033[34m%s\033[0m", name, float64(time.Since(start))/1000000, database.OneLine(query))
+	if err != nil {
+		dst.statementsMu.Lock()
+		delete(dst.statements, name)
+		dst.statementsMu.Unlock()
+		dst.Error(ctx, err)
+		return wrapError(err)
+	}
+
+	return nil
+}
+
+// SelectPrepared executes a statement registered with Prepare and scans the result into the provided data structure.
+// It logs the time taken for the statement execution and the statement name in the same format as Select.
+// The statement is executed on a read replica if one is connected, unless the context is created with WithPrimary.
+//
+// Parameters:
+//   - ctx: The context for the operation, used for cancellation and timeout.
+//   - model: The name of the model being queried, used for logging.
+//   - name: The name of the registered statement.
+//   - data: A pointer to the data structure where the result will be scanned into.
+//   - args: Optional arguments bound to the $1..$n placeholders of the statement.
+//
+// Returns:
+//   - An error if the operation fails, or nil if it succeeds.
+func (dst *PostgresClient) SelectPrepared(ctx context.Context, model string, name string, data any, args ...any) error {
+	return dst.read(ctx, func(client *PostgresClient, q querier) error {
+		return client.selectPrepared(ctx, q, model, name, data, args)
+	})
+}
+
+// ExecPrepared executes a statement registered with Prepare without result, such as INSERT, UPDATE or DELETE.
+// It logs the time taken for the statement execution and the statement name in the same format as Exec.
+//
+// Parameters:
+//   - ctx: The context for the operation, used for cancellation and timeout.
+//   - model: The name of the model being changed, used for logging.
+//   - name: The name of the registered statement.
+//   - args: Optional arguments bound to the $1..$n placeholders of the statement.
+//
+// Returns:
+//   - The number of affected rows.
+//   - An error if the operation fails, or nil if it succeeds.
+func (dst *PostgresClient) ExecPrepared(ctx context.Context, model string, name string, args ...any) (uint, error) {
+	return dst.execPrepared(ctx, dst.client, model, name, args)
+}
+
+// SelectPrepared executes a statement registered with Prepare inside the transaction
+// and scans the result into the provided data structure.
+//
+// Parameters:
+//   - ctx: The context for the operation, used for cancellation and timeout.
+//   - model: The name of the model being queried, used for logging.
+//   - name: The name of the registered statement.
+//   - data: A pointer to the data structure where the result will be scanned into.
+//   - args: Optional arguments bound to the $1..$n placeholders of the statement.
+//
+// Returns:
+//   - An error if the operation fails, or nil if it succeeds.
+func (dst *PostgresTx) SelectPrepared(ctx context.Context, model string, name string, data any, args ...any) error {
+	return dst.client.selectPrepared(ctx, dst.tx, model, name, data, args)
+}
+
+// ExecPrepared executes a statement registered with Prepare inside the transaction without result.
+//
+// Parameters:
+//   - ctx: The context for the operation, used for cancellation and timeout.
+//   - model: The name of the model being changed, used for logging.
+//   - name: The name of the registered statement.
+//   - args: Optional arguments bound to the $1..$n placeholders of the statement.
+//
+// Returns:
+//   - The number of affected rows.
+//   - An error if the operation fails, or nil if it succeeds.
+func (dst *PostgresTx) ExecPrepared(ctx context.Context, model string, name string, args ...any) (uint, error) {
+	return dst.client.execPrepared(ctx, dst.tx, model, name, args)
+}
+
+// selectPrepared executes a registered statement on the given querier and scans the result into data.
+func (dst *PostgresClient) selectPrepared(ctx context.Context, q querier, model string, name string, data any, args []any) error {
+	return dst.withPrepared(ctx, q, name, func(q querier) error {
+		start := time.Now()
+
+		err := pgxscan.Select(ctx, q, data, name, args...)
+		dst.Debug(ctx, "\033[1m\033[36mPG %s Load (%.2f ms)\033[1m \033[34mEXECUTE %s\033[0m%s", model, float64(time.Since(start))/1000000, name, argsToStr(args))
+
+		return wrapError(err)
+	})
+}
+
+// execPrepared executes a registered statement on the given querier and returns the number of affected rows.
+func (dst *PostgresClient) execPrepared(ctx context.Context, q querier, model string, name string, args []any) (uint, error) {
+	var n uint
+	err := dst.withPrepared(ctx, q, name, func(q querier) error {
+		start := time.Now()
+
+		res, err := q.Exec(ctx, name, args...)
+		dst.Debug(ctx, "\033[1m\033[36mPG %s Exec (%.2f ms)\033[1m \033[34mEXECUTE %s\033[0m%s", model, float64(time.Since(start))/1000000, name, argsToStr(args))
+		n = uint(res.RowsAffected())
+
+		return wrapError(err)
+	})
+
+	return n, err
+}
+
+// withPrepared makes sure the registered statement is prepared on the connection used by q and calls fn with it.
+// On the connection pool, a connection is acquired for the duration of fn.
+// Preparing is skipped if the statement was already prepared on the connection.
+func (dst *PostgresClient) withPrepared(ctx context.Context, q querier, name string, fn func(q querier) error) error {
+	dst.statementsMu.RLock()
+	query, ok := dst.statements[name]
+	dst.statementsMu.RUnlock()
+	if !ok {
+		return fmt.Errorf("%w: prepared statement %q is not registered", database.ErrorIncorrectParameters, name)
+	}
+
+	switch v := q.(type) {
+	case *pgxpool.Pool:
+		return wrapError(v.AcquireFunc(ctx, func(conn *pgxpool.Conn) error {
+			if _, err := conn.Conn().Prepare(ctx, name, query); err != nil {
+				return wrapError(err)
+			}
+			return fn(conn)
+		}))
+	case pgx.Tx:
+		if _, err := v.Conn().Prepare(ctx, name, query); err != nil {
+			return wrapError(err)
+		}
+	}
+
+	return fn(q)
+}
+
+// prepareStatements prepares all registered statements on a new connection.
+// It is installed as the AfterConnect hook of the connection pools.
+// A statement that fails to prepare, such as one using a table created by a later migration, is logged and skipped,
+// so the connection stays usable; withPrepared prepares it again and reports the error when it is executed.
+// An error is returned only if the connection is broken.
+func (dst *PostgresClient) prepareStatements(ctx context.Context, conn *pgx.Conn) error {
+	dst.statementsMu.RLock()
+	statements := maps.Clone(dst.statements)
+	dst.statementsMu.RUnlock()
+
+	for name, query := range statements {
+		if _, err := conn.Prepare(ctx, name, query); err != nil {
+			if conn.IsClosed() {
+				return fmt.Errorf("prepare %s: %w", name, err)
+			}
+			dst.Warn(ctx, "PostgreSQL statement %s is not prepared on the new connection: %v", name, err)
+		}
+	}
+
+	return nil
+}
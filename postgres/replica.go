@@ -0,0 +1,85 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// primaryKey is the context key marking read queries that must be executed on the primary server.
+type primaryKey struct{}
+
+// WithPrimary returns a copy of the context that forces read queries to be executed on the primary server,
+// even if read replicas are configured. It is used when a read must see the writes made just before it,
+// which may not be replicated yet.
+//
+// Parameters:
+//   - ctx: The parent context.
+//
+// Returns:
+//   - The context forcing read queries to the primary server.
+func WithPrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, primaryKey{}, true)
+}
+
+// isPrimary reports whether the context forces read queries to the primary server.
+func isPrimary(ctx context.Context) bool {
+	primary, _ := ctx.Value(primaryKey{}).(bool)
+	return primary
+}
+
+// Replica returns the connection pool of the read replicas, or nil if no replica is connected.
+func (dst *PostgresClient) Replica() *pgxpool.Pool {
+	return dst.replica
+}
+
+// connectReplica creates the connection pool of the read replicas.
+// If the replicas are unreachable, a warning is logged and read queries are executed on the primary server.
+func (dst *PostgresClient) connectReplica(ctx context.Context, cfg Config) error {
+	poolConfig, err := cfg.replicaPoolConfig()
+	if err != nil || poolConfig == nil {
+		return err
+	}
+
+	replica, fullVersion, err := dst.newPool(ctx, poolConfig)
+	if err != nil {
+		dst.Warn(ctx, "PostgreSQL replica is unavailable, read queries use the primary server: %v", err)
+		return nil
+	}
+
+	dst.replica = replica
+	dst.Info(ctx, "Connected to PostgreSQL replica: hosts - %v, version %s", poolConfig.ConnConfig.Host, fullVersion)
+
+	return nil
+}
+
+// read executes read queries on the replica pool unless the context forces the primary server.
+// If the replica cannot be reached, fn is executed again on the primary pool.
+func (dst *PostgresClient) read(ctx context.Context, fn func(client *PostgresClient, q querier) error) error {
+	if dst.replica == nil || isPrimary(ctx) {
+		return fn(dst, dst.client)
+	}
+
+	err := fn(dst, dst.replica)
+	if !replicaUnavailable(ctx, err) {
+		return err
+	}
+
+	dst.Warn(ctx, "PostgreSQL replica is unavailable, retry on the primary server: %v", err)
+
+	return fn(dst, dst.client)
+}
+
+// replicaUnavailable reports whether the error means the replica could not be reached
+// and the query was not sent, so it is safe to execute it on the primary server.
+func replicaUnavailable(ctx context.Context, err error) bool {
+	if err == nil || ctx.Err() != nil {
+		return false
+	}
+
+	var connectError *pgconn.ConnectError
+
+	return errors.As(err, &connectError) || pgconn.SafeToRetry(err)
+}
@@ -0,0 +1,102 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"math"
+	"math/rand/v2"
+	"slices"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+var (
+	// DefaultRetryCodes: are the SQLSTATE codes retried when RetryPolicy.Codes is empty:
+	// serialization_failure (40001) and deadlock_detected (40P01).
+	DefaultRetryCodes = []string{"40001", "40P01"}
+)
+
+// RetryPolicy defines how PostgreSQL transactions failing with transient errors are retried.
+// The whole transaction function is rerun on each attempt, so it must be safe to call several times.
+// The zero value disables retries.
+type RetryPolicy struct {
+	MaxAttempts int           // MaxAttempts: is the total number of attempts, including the first one. Values less than 2 disable retries.
+	BaseDelay   time.Duration // BaseDelay: is the delay before the first retry. It is doubled for each subsequent retry.
+	MaxDelay    time.Duration // MaxDelay: is the upper bound of the delay between attempts. Zero means no bound.
+	Codes       []string      // Codes: is the list of SQLSTATE codes to retry. DefaultRetryCodes are used if empty.
+}
+
+// retryCode returns the SQLSTATE code of the error if the policy allows retrying it.
+// It returns an empty string if the error is not a PostgreSQL error or its code is not in the list.
+//
+// Parameters:
+//   - err: The error returned by the transaction.
+//
+// Returns:
+//   - The SQLSTATE code of the retryable error, or an empty string.
+func (dst *RetryPolicy) retryCode(err error) string {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return ""
+	}
+
+	codes := dst.Codes
+	if len(codes) == 0 {
+		codes = DefaultRetryCodes
+	}
+
+	if !slices.Contains(codes, pgErr.Code) {
+		return ""
+	}
+
+	return pgErr.Code
+}
+
+// delay returns the backoff delay before the given retry attempt.
+// The delay grows exponentially from BaseDelay, is limited by MaxDelay
+// and is randomized between half and the full value to spread concurrent retries.
+//
+// Parameters:
+//   - attempt: The number of the failed attempt, starting from 1.
+//
+// Returns:
+//   - The time to wait before the next attempt.
+func (dst *RetryPolicy) delay(attempt int) time.Duration {
+	if dst.BaseDelay <= 0 {
+		return 0
+	}
+
+	d := dst.BaseDelay
+	for i := 1; i < attempt && d < math.MaxInt64/2; i++ {
+		d *= 2
+		if dst.MaxDelay > 0 && d >= dst.MaxDelay {
+			break
+		}
+	}
+	if dst.MaxDelay > 0 && d > dst.MaxDelay {
+		d = dst.MaxDelay
+	}
+
+	return d/2 + rand.N(d/2+1)
+}
+
+// sleep waits for the given duration or until the context is done.
+//
+// Parameters:
+//   - ctx: The context for the operation, used for cancellation and timeout.
+//   - d: The duration to wait.
+//
+// Returns:
+//   - The context error if the context is done before the duration elapses, or nil.
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,57 @@
+package postgres
+
+import (
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// dbField describes a struct field mapped to a database column with a `db` tag.
+type dbField struct {
+	Column  string   // Column: is the name of the database column, taken from the `db` tag.
+	Index   []int    // Index: is the index sequence of the field for reflect.Value.FieldByIndex.
+	Options []string // Options: are the comma-separated options following the column name in the tag.
+}
+
+// HasOption reports whether the tag of the field contains the given option.
+func (dst *dbField) HasOption(option string) bool {
+	return slices.Contains(dst.Options, option)
+}
+
+// dbFields returns the fields of a struct type mapped to database columns with `db` tags.
+// Untagged and unexported fields, as well as fields tagged with `db:"-"`, are skipped.
+// Fields of embedded structs without a tag are flattened into the result, like scany does when scanning.
+//
+// Parameters:
+//   - t: The struct type to inspect.
+//
+// Returns:
+//   - A slice of dbField in the order of declaration.
+func dbFields(t reflect.Type) []dbField {
+	res := []dbField{}
+	for i := range t.NumField() {
+		field := t.Field(i)
+		tag, ok := field.Tag.Lookup("db")
+
+		if !ok && field.Anonymous && field.Type.Kind() == reflect.Struct {
+			for _, sub := range dbFields(field.Type) {
+				sub.Index = append([]int{i}, sub.Index...)
+				res = append(res, sub)
+			}
+			continue
+		}
+
+		if !ok || tag == "-" || !field.IsExported() {
+			continue
+		}
+
+		parts := strings.Split(tag, ",")
+		if parts[0] == "" {
+			continue
+		}
+
+		res = append(res, dbField{Column: parts[0], Index: []int{i}, Options: parts[1:]})
+	}
+
+	return res
+}
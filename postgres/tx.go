@@ -0,0 +1,331 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ra-company/database"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// PostgresTx is a handle to an open PostgreSQL transaction.
+// It is created by PostgresClient.WithTx and is valid only inside the callback passed to it.
+// All statements executed through the handle belong to the same transaction and are logged
+// in the same format as the statements executed by the PostgresClient.
+// Calling WithTx on the handle starts a nested transaction implemented with a savepoint.
+type PostgresTx struct {
+	client     *PostgresClient // client: is the PostgreSQL client that started the transaction, used for logging.
+	tx         pgx.Tx          // tx: is the underlying pgx transaction or savepoint.
+	savepoints *int64          // savepoints: is the number of savepoints created in the top-level transaction, shared by all nested handles.
+	savepoint  string          // savepoint: is the name of the savepoint, empty for the top-level transaction.
+}
+
+// WithTx runs the provided function inside a PostgreSQL transaction.
+// The transaction is committed if the function returns nil and rolled back if it returns an error or panics.
+// In case of a panic the transaction is rolled back and the panic is propagated to the caller.
+// If the transaction fails with an error allowed by the client RetryPolicy (serialization failure or deadlock by default),
+// the whole transaction, including the function, is run again after a backoff delay.
+// BEGIN, COMMIT and ROLLBACK statements are logged with the time taken for each step for debugging purposes.
+//
+// Parameters:
+//   - ctx: The context for the operation, used for cancellation and timeout.
+//   - opts: The transaction options, such as isolation level and access mode.
+//   - fn: The function to run inside the transaction. It receives the transaction handle.
+//
+// Returns:
+//   - An error returned by the function, or an error if the transaction could not be started or committed.
+func (dst *PostgresClient) WithTx(ctx context.Context, opts pgx.TxOptions, fn func(tx *PostgresTx) error) error {
+	for attempt := 1; ; attempt++ {
+		err := dst.beginTx(ctx, opts, fn)
+		if err == nil || attempt >= dst.RetryPolicy.MaxAttempts {
+			return err
+		}
+
+		code := dst.RetryPolicy.retryCode(err)
+		if code == "" {
+			return err
+		}
+
+		delay := dst.RetryPolicy.delay(attempt)
+		dst.Warn(ctx, "PostgreSQL transaction failed with SQLSTATE %s, retry %d of %d in %.2f ms: %v", code, attempt, dst.RetryPolicy.MaxAttempts-1, float64(delay)/1000000, err)
+		if err := sleep(ctx, delay); err != nil {
+			return err
+		}
+	}
+}
+
+// beginTx starts a PostgreSQL transaction and runs the provided function inside it once.
+func (dst *PostgresClient) beginTx(ctx context.Context, opts pgx.TxOptions, fn func(tx *PostgresTx) error) error {
+	start := time.Now()
+
+	tx, err := dst.client.BeginTx(ctx, opts)
+	dst.logTx(ctx, start, "\033[35m", "BEGIN")
+	if err != nil {
+		return wrapError(err)
+	}
+
+	return (&PostgresTx{client: dst, tx: tx, savepoints: new(int64)}).run(ctx, fn)
+}
+
+// WithTx runs the provided function inside a nested transaction implemented with a SAVEPOINT.
+// The savepoint is released if the function returns nil and the transaction is rolled back to it
+// if the function returns an error or panics, leaving the outer transaction usable.
+// The signature matches PostgresClient.WithTx so that functions can be composed regardless
+// of whether they are called inside a transaction or not. The transaction options are ignored,
+// as savepoints inherit the characteristics of the outer transaction.
+// SAVEPOINT, RELEASE SAVEPOINT and ROLLBACK TO SAVEPOINT statements are logged with the savepoint name
+// and the time taken for each step for debugging purposes.
+//
+// Parameters:
+//   - ctx: The context for the operation, used for cancellation and timeout.
+//   - opts: The transaction options, ignored for nested transactions.
+//   - fn: The function to run inside the nested transaction. It receives the nested transaction handle.
+//
+// Returns:
+//   - An error returned by the function, or an error if the savepoint could not be created or released.
+func (dst *PostgresTx) WithTx(ctx context.Context, opts pgx.TxOptions, fn func(tx *PostgresTx) error) error {
+	start := time.Now()
+
+	tx, err := dst.tx.Begin(ctx)
+	if errors.Is(err, pgx.ErrTxClosed) {
+		return err
+	}
+
+	// pgx names savepoints sp_1, sp_2, ... in the order they are created in the top-level transaction.
+	*dst.savepoints++
+	savepoint := fmt.Sprintf("sp_%d", *dst.savepoints)
+	dst.client.logTx(ctx, start, "\033[35m", "SAVEPOINT "+savepoint)
+	if err != nil {
+		return wrapError(err)
+	}
+
+	return (&PostgresTx{client: dst.client, tx: tx, savepoints: dst.savepoints, savepoint: savepoint}).run(ctx, fn)
+}
+
+// run calls the function with the transaction handle and finishes the transaction depending on the result.
+// The transaction is committed if the function returns nil and rolled back if it returns an error or panics.
+func (dst *PostgresTx) run(ctx context.Context, fn func(tx *PostgresTx) error) (err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			dst.rollback(ctx)
+			panic(p)
+		}
+	}()
+
+	if err = fn(dst); err != nil {
+		dst.rollback(ctx)
+		return err
+	}
+
+	start := time.Now()
+
+	err = dst.tx.Commit(ctx)
+	if dst.savepoint != "" {
+		dst.client.logTx(ctx, start, "\033[35m", "RELEASE SAVEPOINT "+dst.savepoint)
+	} else {
+		dst.client.logTx(ctx, start, "\033[35m", "COMMIT")
+	}
+
+	return wrapError(err)
+}
+
+// rollback rolls back the transaction and logs the time taken.
+func (dst *PostgresTx) rollback(ctx context.Context) {
+	start := time.Now()
+
+	dst.tx.Rollback(ctx)
+	if dst.savepoint != "" {
+		dst.client.logTx(ctx, start, "\033[31m", "ROLLBACK TO SAVEPOINT "+dst.savepoint)
+	} else {
+		dst.client.logTx(ctx, start, "\033[31m", "ROLLBACK")
+	}
+}
+
+// Select data from database inside the transaction and scan into data structure.
+// It works the same way as PostgresClient.Select, but the query is executed within the transaction.
+//
+// Parameters:
+//   - ctx: The context for the operation, used for cancellation and timeout.
+//   - model: The name of the model being queried, used for logging.
+//   - query: The SQL query string to be executed in the database.
+//   - data: A pointer to the data structure where the result will be scanned into.
+//   - args: Optional arguments bound to the $1..$n placeholders of the query.
+//
+// Returns:
+//   - An error if the operation fails, or nil if it succeeds.
+func (dst *PostgresTx) Select(ctx context.Context, model string, query string, data any, args ...any) error {
+	return dst.client.selectRows(ctx, dst.tx, model, query, data, args)
+}
+
+// SelectEach selects data from database inside the transaction and scans it row by row into the provided data structure.
+// It works the same way as PostgresClient.SelectEach, but the query is executed within the transaction.
+//
+// Parameters:
+//   - ctx: The context for the operation, used for cancellation and timeout.
+//   - model: The name of the model being queried, used for logging.
+//   - query: The SQL query string to be executed in the database.
+//   - data: A pointer to the data structure each row will be scanned into.
+//   - fn: The function called after each row is scanned.
+//   - args: Optional arguments bound to the $1..$n placeholders of the query.
+//
+// Returns:
+//   - An error returned by fn, or an error if the operation fails, or nil if it succeeds.
+func (dst *PostgresTx) SelectEach(ctx context.Context, model string, query string, data any, fn func() error, args ...any) error {
+	return dst.client.selectEach(ctx, dst.tx, model, query, data, fn, args)
+}
+
+// Insert data into database inside the transaction and return inserted IDs.
+// " RETURNING id" is appended to the query to collect the IDs of the inserted records.
+//
+// Parameters:
+//   - ctx: The context for the operation, used for cancellation and timeout.
+//   - model: The name of the model being inserted, used for logging.
+//   - query: The SQL query string for inserting data into the database.
+//   - args: Optional arguments bound to the $1..$n placeholders of the query.
+//
+// Returns:
+//   - A slice of uint containing the IDs of the inserted records.
+//   - An error if the operation fails, or nil if it succeeds.
+func (dst *PostgresTx) Insert(ctx context.Context, model string, query string, args ...any) ([]uint, error) {
+	return insertReturning[uint](ctx, dst.client, dst.tx, model, query, args)
+}
+
+// InsertUUID data into database inside the transaction and return inserted UUIDs.
+// " RETURNING id" is appended to the query to collect the UUIDs of the inserted records.
+//
+// Parameters:
+//   - ctx: The context for the operation, used for cancellation and timeout.
+//   - model: The name of the model being inserted, used for logging.
+//   - query: The SQL query string for inserting data into the database.
+//   - args: Optional arguments bound to the $1..$n placeholders of the query.
+//
+// Returns:
+//   - A slice of uuid.UUID containing the UUIDs of the inserted records.
+//   - An error if the operation fails, or nil if it succeeds.
+func (dst *PostgresTx) InsertUUID(ctx context.Context, model string, query string, args ...any) ([]uuid.UUID, error) {
+	return insertReturning[uuid.UUID](ctx, dst.client, dst.tx, model, query, args)
+}
+
+// Update data in database inside the transaction and return affected rows count.
+// It returns database.ErrorIncorrectRequest if the query is not an UPDATE statement.
+//
+// Parameters:
+//   - ctx: The context for the operation, used for cancellation and timeout.
+//   - model: The name of the model being updated, used for logging.
+//   - query: The SQL query string for updating data in the database.
+//   - args: Optional arguments bound to the $1..$n placeholders of the query.
+//
+// Returns:
+//   - A uint representing the number of affected rows.
+//   - An error if the operation fails, or nil if it succeeds.
+func (dst *PostgresTx) Update(ctx context.Context, model string, query string, args ...any) (uint, error) {
+	if query == "" {
+		return 0, nil
+	}
+
+	res, err := dst.client.execTag(ctx, dst.tx, model, "Update", "\033[33m", query, args)
+	if err != nil {
+		dst.client.Error(ctx, err)
+		return 0, err
+	}
+
+	if !res.Update() {
+		dst.client.Error(ctx, database.ErrorIncorrectRequest)
+		return 0, database.ErrorIncorrectRequest
+	}
+
+	return uint(res.RowsAffected()), nil
+}
+
+// Delete data from database inside the transaction and return affected rows count.
+// It returns database.ErrorIncorrectRequest if the query is not a DELETE statement.
+//
+// Parameters:
+//   - ctx: The context for the operation, used for cancellation and timeout.
+//   - model: The name of the model being deleted, used for logging.
+//   - query: The SQL query string for deleting data from the database.
+//   - args: Optional arguments bound to the $1..$n placeholders of the query.
+//
+// Returns:
+//   - A uint representing the number of affected rows.
+//   - An error if the operation fails, or nil if it succeeds.
+func (dst *PostgresTx) Delete(ctx context.Context, model string, query string, args ...any) (uint, error) {
+	res, err := dst.client.execTag(ctx, dst.tx, model, "Delete", "\033[31m", query, args)
+	if err != nil {
+		dst.client.Error(ctx, err)
+		return 0, err
+	}
+
+	if !res.Delete() {
+		dst.client.Error(ctx, database.ErrorIncorrectRequest)
+		return 0, database.ErrorIncorrectRequest
+	}
+
+	return uint(res.RowsAffected()), nil
+}
+
+// Count returns records count in database inside the transaction.
+//
+// Parameters:
+//   - ctx: The context for the operation, used for cancellation and timeout.
+//   - model: The name of the model being counted, used for logging.
+//   - query: The SQL query string for counting records in the database.
+//   - args: Optional arguments bound to the $1..$n placeholders of the query.
+//
+// Returns:
+//   - A uint64 representing the number of records in the specified model.
+//   - An error if the operation fails, or nil if it succeeds.
+func (dst *PostgresTx) Count(ctx context.Context, model string, query string, args ...any) (uint64, error) {
+	return dst.client.queryUint64(ctx, dst.tx, model, "Count", query, args)
+}
+
+// Max returns maximum field value in database inside the transaction.
+//
+// Parameters:
+//   - ctx: The context for the operation, used for cancellation and timeout.
+//   - model: The name of the model being queried, used for logging.
+//   - query: The SQL query string for finding the maximum value in the database.
+//   - args: Optional arguments bound to the $1..$n placeholders of the query.
+//
+// Returns:
+//   - A uint64 representing the maximum value of the specified field in the model.
+//   - An error if the operation fails, or nil if it succeeds.
+func (dst *PostgresTx) Max(ctx context.Context, model, query string, args ...any) (uint64, error) {
+	return dst.client.queryUint64(ctx, dst.tx, model, "MAX", query, args)
+}
+
+// Exec executes a query without result inside the transaction.
+//
+// Parameters:
+//   - ctx: The context for the operation, used for cancellation and timeout.
+//   - model: The name of the model being queried, used for logging.
+//   - query: The SQL query string to be executed in the database.
+//   - args: Optional arguments bound to the $1..$n placeholders of the query.
+//
+// Returns:
+//   - An error if the operation fails, or nil if it succeeds.
+func (dst *PostgresTx) Exec(ctx context.Context, model string, query string, args ...any) error {
+	_, err := dst.client.execTag(ctx, dst.tx, model, "Exec", "\033[34m", query, args)
+	return err
+}
+
+// Tx returns the underlying pgx transaction.
+// It is used to access pgx functionality that is not covered by the PostgresTx methods.
+func (dst *PostgresTx) Tx() pgx.Tx {
+	return dst.tx
+}
+
+// logTx puts a transaction control statement (BEGIN, COMMIT, ROLLBACK, SAVEPOINT) to the log.
+//
+// Parameters:
+//   - ctx: The context for the operation, used for logging.
+//   - start: The start time of the statement execution.
+//   - color: The terminal color sequence used for the statement.
+//   - statement: The statement to be logged.
+func (dst *PostgresClient) logTx(ctx context.Context, start time.Time, color, statement string) {
+	dst.Debug(ctx, "\033[1m\033[36mPG TRANSACTION (%.2f ms)\033[0m \033[1m%s%s\033[0m", float64(time.Since(start))/1000000, color, statement)
+}
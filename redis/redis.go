@@ -4,7 +4,6 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"log"
 	"strings"
 	"time"
 
@@ -24,12 +23,13 @@ type Set struct {
 }
 
 type RedisClient struct {
-	logging.CustomLogger                      // CustomLogger: is an interface that allows the Redis client to use a custom logger for logging operations and errors.
-	client               *redis.Client        // client: is the Redis client used to interact with the Redis server.
-	cluster              *redis.ClusterClient // cluster: is a Redis cluster client used for connecting to a Redis cluster.
-	singlePush           *redis.Script        // singlePush: is a Lua script used for atomic operations on Redis lists, specifically for pushing a value to a list only if the list is empty.
-	db                   int                  // db: is the Redis database number, used for logging purposes.
-	DoNotLogQueries      bool                 // DoNotLogQueries: is a flag that indicates whether to log Redis queries or not. If true, queries will not be logged, which can be useful for performance or security reasons.
+	logging.CustomLogger                       // CustomLogger: is an interface that allows the Redis client to use a custom logger for logging operations and errors.
+	client               *redis.Client         // client: is the Redis client used to interact with the Redis server.
+	cluster              *redis.ClusterClient  // cluster: is a Redis cluster client used for connecting to a Redis cluster.
+	singlePush           *redis.Script         // singlePush: is a Lua script used for atomic operations on Redis lists, specifically for pushing a value to a list only if the list is empty.
+	db                   int                   // db: is the Redis database number, used for logging purposes.
+	DoNotLogQueries      bool                  // DoNotLogQueries: is a flag that indicates whether to log Redis queries or not. If true, queries will not be logged, which can be useful for performance or security reasons.
+	ConnectRetry         database.ConnectRetry // ConnectRetry: defines how a failed connection to the server is retried by Connect and Start.
 }
 
 var (
@@ -42,6 +42,7 @@ var (
 // Start initializes the Redis client with the provided host, port, password, and database number.
 // It connects to the Redis server and checks the connection by sending a PING command.
 // If the connection fails, it logs a fatal error and exits the program.
+// Use Connect to handle connection errors without exiting the program.
 //
 // Parameters:
 //   - ctx: The context for the operation, allowing for cancellation and timeouts.
@@ -55,14 +56,25 @@ var (
 // This function should be called at the start of the application to establish a connection to Redis.
 // It sets the usedDB variable to the current database number for logging purposes.
 func (dst *RedisClient) Start(ctx context.Context, hosts string, password string, db int) {
-	if strings.Contains(hosts, ",") {
-		// If the hosts contain a comma, it is a cluster of Redis nodes.
-		dst.startCluster(ctx, hosts, password)
-	} else {
-		// If the hosts do not contain a comma, it is a single Redis node.
-		dst.startSingle(ctx, hosts, password, db)
+	if err := dst.Connect(ctx, hosts, password, db); err != nil {
+		dst.Fatal(ctx, "Failed to connect to Redis: %v", err)
 	}
+}
 
+// Connect initializes the Redis client with the provided host, port, password, and database number.
+// It works the same way as Start, but returns an error instead of exiting the program if the connection fails,
+// so the caller decides whether a failed connection is fatal.
+// The connection is retried according to the ConnectRetry settings of the client.
+//
+// Parameters:
+//   - ctx: The context for the operation, allowing for cancellation and timeouts.
+//   - hosts: The Redis server hosts (single or comma-separated).
+//   - password: The Redis server password.
+//   - db: The Redis database number to use.
+//
+// Returns:
+//   - An error if the connection fails, or nil if it succeeds.
+func (dst *RedisClient) Connect(ctx context.Context, hosts string, password string, db int) error {
 	dst.singlePush = redis.NewScript(`
         if redis.call("LLEN", KEYS[1]) == 0 then
             return redis.call("LPUSH", KEYS[1], ARGV[1])
@@ -70,51 +82,72 @@ func (dst *RedisClient) Start(ctx context.Context, hosts string, password string
             return -1
         end
     `)
-}
 
-func (dst *RedisClient) startSingle(ctx context.Context, host string, password string, db int) {
-	dst.db = db // Set the usedDB variable to the current database number.
+	return dst.ConnectRetry.Do(ctx, func() error {
+		if strings.Contains(hosts, ",") {
+			// If the hosts contain a comma, it is a cluster of Redis nodes.
+			return dst.connectCluster(ctx, hosts, password)
+		}
+		// If the hosts do not contain a comma, it is a single Redis node.
+		return dst.connectSingle(ctx, hosts, password, db)
+	}, func(err error, attempt int, delay time.Duration) {
+		dst.Warn(ctx, "Redis connection attempt %d of %d failed, retry in %.2f ms: %v", attempt, dst.ConnectRetry.Attempts, float64(delay)/1000000, err)
+	})
+}
 
-	dst.client = redis.NewClient(&redis.Options{
+func (dst *RedisClient) connectSingle(ctx context.Context, host string, password string, db int) error {
+	client := redis.NewClient(&redis.Options{
 		Addr:     host,
 		Password: password,
-		DB:       dst.db,
+		DB:       db,
 	})
-	res := dst.client.Ping(ctx)
+	res := client.Ping(ctx)
 	if res.Err() != nil {
-		dst.Fatal(ctx, "Failed to connect to Redis database: %v", res.Err())
+		client.Close()
+		return fmt.Errorf("failed to connect to Redis database: %w", res.Err())
 	}
 
-	info, err := dst.client.Info(ctx, "server").Result()
+	info, err := client.Info(ctx, "server").Result()
 	if err != nil {
-		log.Fatalf("Failed to get redis info: %v", err)
+		client.Close()
+		return fmt.Errorf("failed to get redis info: %w", err)
 	}
 
+	dst.db = db // Set the usedDB variable to the current database number.
+	dst.client = client
+	dst.cluster = nil // Ensure cluster is nil for single instance.
+
 	dst.Info(ctx, "Connected to Redis database: redis://%v/%v", host, dst.db)
 	dst.logServerInfo(ctx, info)
-	dst.cluster = nil // Ensure cluster is nil for single instance.
-}
 
-func (dst *RedisClient) startCluster(ctx context.Context, hosts string, password string) {
-	dst.db = 0 // Set the usedDB variable to 0 for cluster, as clusters do not use a specific database number.
+	return nil
+}
 
-	dst.cluster = redis.NewClusterClient(&redis.ClusterOptions{
+func (dst *RedisClient) connectCluster(ctx context.Context, hosts string, password string) error {
+	cluster := redis.NewClusterClient(&redis.ClusterOptions{
 		Addrs:    strings.Split(hosts, ","),
 		Password: password,
 	})
-	res := dst.cluster.Ping(ctx)
+	res := cluster.Ping(ctx)
 	if res.Err() != nil {
-		dst.Fatal(ctx, "Failed to connect to Redis cluster: %v", res.Err())
+		cluster.Close()
+		return fmt.Errorf("failed to connect to Redis cluster: %w", res.Err())
 	}
 
-	info, err := dst.cluster.Info(ctx, "server").Result()
+	info, err := cluster.Info(ctx, "server").Result()
 	if err != nil {
-		log.Fatalf("Failed to get redis info: %v", err)
+		cluster.Close()
+		return fmt.Errorf("failed to get redis info: %w", err)
 	}
 
+	dst.db = 0 // Set the usedDB variable to 0 for cluster, as clusters do not use a specific database number.
+	dst.cluster = cluster
+	dst.client = nil // Ensure client is nil for cluster.
+
 	dst.Info(ctx, "Connected to Redis cluster: redis://%v", hosts)
 	dst.logServerInfo(ctx, info)
-	dst.client = nil // Ensure client is nil for cluster.
+
+	return nil
 }
 
 func (dst *RedisClient) logServerInfo(ctx context.Context, info string) {
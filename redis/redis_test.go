@@ -8,6 +8,7 @@ import (
 
 	"github.com/brianvoe/gofakeit/v7"
 	"github.com/google/uuid"
+	"github.com/ra-company/database"
 	"github.com/ra-company/env"
 	"github.com/ra-company/logging"
 	"github.com/redis/go-redis/v9"
@@ -278,3 +279,9 @@ func tests(t *testing.T, hosts, password string, db int) {
 		require.Empty(t, str, "XAdd() with different stream")
 	})
 }
+
+func TestConnectError(t *testing.T) {
+	client := RedisClient{ConnectRetry: database.ConnectRetry{Attempts: 2, Delay: time.Millisecond}}
+	err := client.Connect(t.Context(), "127.0.0.1:1", "", 0)
+	require.Error(t, err, "Connect() should return an error for an unreachable server")
+}